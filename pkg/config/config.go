@@ -14,9 +14,27 @@ type LSPConfig struct {
 	Options  any      `json:"options"`
 }
 
+// GrepDefaults holds the selector settings grep falls back to when a
+// request doesn't override them, so a workspace can e.g. permanently skip
+// vendored directories or cap scanned file size without every grep call
+// having to pass exclude/max_file_size.
+type GrepDefaults struct {
+	// MaxFileSize caps how large a file the regex fallback will read, in
+	// bytes. 0 means use the tool's built-in default.
+	MaxFileSize int64 `json:"maxFileSize"`
+	// Exclude lists glob patterns (matched against the base name) that are
+	// always skipped, e.g. "*.min.js".
+	Exclude []string `json:"exclude"`
+}
+
 type Config struct {
-	DebugLSP bool                 `json:"debugLsp"`
+	DebugLSP bool `json:"debugLsp"`
+	// LogLevel sets the minimum level the logger emits: "debug", "info",
+	// "warn", or "error". Defaults to "info" when empty. DebugLSP=true
+	// always forces "debug" regardless of this value.
+	LogLevel string               `json:"logLevel"`
 	LSP      map[string]LSPConfig `json:"lsp"`
+	Grep     GrepDefaults         `json:"grep"`
 }
 
 var (
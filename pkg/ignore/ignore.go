@@ -0,0 +1,221 @@
+// Package ignore parses .gitignore-style exclude files and exposes a
+// shared matcher that the grep, glob, and workspace-watching tools can all
+// use to decide whether a path should be skipped.
+package ignore
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// ignoreFileNames are read, in this order, from the root of an Ignore.
+// Later files are appended after earlier ones, so a later file's negation
+// patterns can override an earlier file's ignores.
+var ignoreFileNames = []string{".gitignore", ".ignore", ".lspignore"}
+
+type pattern struct {
+	negate  bool
+	dirOnly bool
+	glob    string
+	source  string
+}
+
+// Ignore matches paths under root against the combined patterns from
+// .gitignore, .ignore, and .lspignore. It implements fs.FS so callers can
+// walk a tree with ignored paths already hidden.
+type Ignore struct {
+	root     string
+	patterns []pattern
+}
+
+var _ fs.FS = (*Ignore)(nil)
+
+// New loads and compiles the ignore files found directly under root. A
+// missing file is not an error; Ignore simply has no patterns from it.
+func New(root string) (*Ignore, error) {
+	ig := &Ignore{root: filepath.Clean(root)}
+	for _, name := range ignoreFileNames {
+		if err := ig.loadFile(filepath.Join(ig.root, name), name); err != nil {
+			return nil, err
+		}
+	}
+	return ig, nil
+}
+
+// Root returns the directory Ignore was built from.
+func (ig *Ignore) Root() string {
+	return ig.root
+}
+
+// HasPatterns reports whether any ignore file contributed at least one
+// pattern, so callers can fall back to a hardcoded skip list when the
+// workspace has no ignore files of its own.
+func (ig *Ignore) HasPatterns() bool {
+	return len(ig.patterns) > 0
+}
+
+func (ig *Ignore) loadFile(path, source string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for i, raw := range strings.Split(string(data), "\n") {
+		line := strings.TrimRight(raw, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p, ok := parsePattern(line)
+		if !ok {
+			continue
+		}
+		p.source = fmt.Sprintf("%s:%d", source, i+1)
+		ig.patterns = append(ig.patterns, p)
+	}
+	return nil
+}
+
+// parsePattern compiles a single gitignore-style line into a doublestar
+// glob. A pattern with no "/" (other than a trailing one) matches at any
+// depth, so it is prefixed with "**/"; a pattern containing a "/" elsewhere
+// is anchored to the ignore file's directory and used as-is.
+func parsePattern(line string) (pattern, bool) {
+	var p pattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	}
+	if line == "" {
+		return pattern{}, false
+	}
+	anchored := strings.HasPrefix(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return pattern{}, false
+	}
+	if !anchored && !strings.Contains(line, "/") {
+		p.glob = "**/" + line
+	} else {
+		p.glob = line
+	}
+	return p, true
+}
+
+// Match reports whether path (absolute, or relative to root) is ignored,
+// and the source ignore-file line responsible, e.g. ".gitignore:12". The
+// last matching pattern wins, so a later negation un-ignores an earlier
+// match. Callers are expected to call Match while walking a tree top-down
+// and skip descending into ignored directories themselves; that walk order
+// is what gives ignored directories their "everything beneath is ignored"
+// behavior, mirroring how filepath.Walk + SkipDir already works.
+func (ig *Ignore) Match(path string) (ignored bool, fromPattern string) {
+	rel := ig.relPath(path)
+	if rel == "" {
+		return false, ""
+	}
+	rel = filepath.ToSlash(rel)
+
+	for _, p := range ig.patterns {
+		if p.dirOnly && !ig.isDir(path) {
+			continue
+		}
+		matched, err := doublestar.Match(p.glob, rel)
+		if err != nil || !matched {
+			continue
+		}
+		ignored = !p.negate
+		if ignored {
+			fromPattern = p.source
+		} else {
+			fromPattern = ""
+		}
+	}
+	return ignored, fromPattern
+}
+
+func (ig *Ignore) relPath(path string) string {
+	abs := path
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(ig.root, abs)
+	}
+	rel, err := filepath.Rel(ig.root, abs)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return rel
+}
+
+func (ig *Ignore) isDir(path string) bool {
+	abs := path
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(ig.root, abs)
+	}
+	info, err := os.Stat(abs)
+	return err == nil && info.IsDir()
+}
+
+// Open implements fs.FS, hiding ignored paths from the underlying OS
+// filesystem rooted at root.
+func (ig *Ignore) Open(name string) (fs.File, error) {
+	if ignored, _ := ig.Match(name); ignored {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return os.DirFS(ig.root).Open(name)
+}
+
+// ReadDir implements fs.ReadDirFS, leaving ignored entries out of the
+// listing so fs.WalkDir neither reports nor descends into them.
+func (ig *Ignore) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(os.DirFS(ig.root), name)
+	if err != nil {
+		return nil, err
+	}
+	kept := entries[:0]
+	for _, e := range entries {
+		if ignored, _ := ig.Match(filepath.Join(name, e.Name())); !ignored {
+			kept = append(kept, e)
+		}
+	}
+	return kept, nil
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[string]*Ignore{}
+)
+
+// ForDir returns the compiled Ignore for dir, building and caching it on
+// first use so repeated lookups during a large tree walk don't re-parse
+// and re-compile the same ignore files.
+func ForDir(dir string) (*Ignore, error) {
+	dir = filepath.Clean(dir)
+
+	cacheMu.Lock()
+	ig, ok := cache[dir]
+	cacheMu.Unlock()
+	if ok {
+		return ig, nil
+	}
+
+	ig, err := New(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheMu.Lock()
+	cache[dir] = ig
+	cacheMu.Unlock()
+	return ig, nil
+}
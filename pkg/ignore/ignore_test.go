@@ -0,0 +1,133 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeIgnoreFile(t *testing.T, dir, name, contents string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
+
+func TestMatchBasicPatterns(t *testing.T) {
+	dir := t.TempDir()
+	writeIgnoreFile(t, dir, ".gitignore", "*.log\n/build\nnode_modules\n")
+
+	ig, err := New(dir)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		path    string
+		ignored bool
+	}{
+		{"app.log", true},
+		{"src/app.log", true},
+		{"build", true},
+		{"src/build", false},
+		{"node_modules", true},
+		{"src/node_modules", true},
+		{"main.go", false},
+	}
+	for _, tt := range tests {
+		got, _ := ig.Match(filepath.Join(dir, tt.path))
+		if got != tt.ignored {
+			t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.ignored)
+		}
+	}
+}
+
+func TestMatchNegationOverridesEarlierIgnore(t *testing.T) {
+	dir := t.TempDir()
+	writeIgnoreFile(t, dir, ".gitignore", "*.log\n!keep.log\n")
+
+	ig, err := New(dir)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if ignored, _ := ig.Match(filepath.Join(dir, "debug.log")); !ignored {
+		t.Errorf("debug.log should be ignored")
+	}
+	if ignored, _ := ig.Match(filepath.Join(dir, "keep.log")); ignored {
+		t.Errorf("keep.log should be un-ignored by the negation pattern")
+	}
+}
+
+func TestMatchLaterFileOverridesEarlier(t *testing.T) {
+	dir := t.TempDir()
+	writeIgnoreFile(t, dir, ".gitignore", "*.log\n")
+	writeIgnoreFile(t, dir, ".lspignore", "!important.log\n")
+
+	ig, err := New(dir)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if ignored, _ := ig.Match(filepath.Join(dir, "important.log")); ignored {
+		t.Errorf("important.log should be un-ignored by .lspignore, loaded after .gitignore")
+	}
+}
+
+func TestMatchDirOnlyPattern(t *testing.T) {
+	dir := t.TempDir()
+	writeIgnoreFile(t, dir, ".gitignore", "dist/\n")
+	if err := os.MkdirAll(filepath.Join(dir, "dist"), 0o755); err != nil {
+		t.Fatalf("mkdir dist: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "dist.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("write dist.txt: %v", err)
+	}
+
+	ig, err := New(dir)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if ignored, _ := ig.Match(filepath.Join(dir, "dist")); !ignored {
+		t.Errorf("dist directory should be ignored by the dirOnly pattern")
+	}
+	if ignored, _ := ig.Match(filepath.Join(dir, "dist.txt")); ignored {
+		t.Errorf("dist.txt file should not match the dirOnly pattern dist/")
+	}
+}
+
+func TestHasPatternsReflectsWhetherAnyFileContributed(t *testing.T) {
+	empty := t.TempDir()
+	ig, err := New(empty)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if ig.HasPatterns() {
+		t.Errorf("HasPatterns() = true for a workspace with no ignore files")
+	}
+
+	nonEmpty := t.TempDir()
+	writeIgnoreFile(t, nonEmpty, ".gitignore", "*.tmp\n")
+	ig, err = New(nonEmpty)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if !ig.HasPatterns() {
+		t.Errorf("HasPatterns() = false for a workspace with a non-empty .gitignore")
+	}
+}
+
+func TestMatchOutsideRootIsNeverIgnored(t *testing.T) {
+	dir := t.TempDir()
+	writeIgnoreFile(t, dir, ".gitignore", "*\n")
+
+	ig, err := New(dir)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if ignored, _ := ig.Match(filepath.Join(dir, "..", "outside.txt")); ignored {
+		t.Errorf("a path outside root should never be reported as ignored")
+	}
+}
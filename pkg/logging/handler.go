@@ -0,0 +1,127 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// multiHandler fans a record out to every handler it wraps, so a single
+// logger can write to stderr, a JSON file, and the in-memory ring buffer
+// at once.
+type multiHandler struct {
+	handlers []slog.Handler
+}
+
+func (m *multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m.handlers {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var firstErr error
+	for _, h := range m.handlers {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, r.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (m *multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := make([]slog.Handler, len(m.handlers))
+	for i, h := range m.handlers {
+		next[i] = h.WithAttrs(attrs)
+	}
+	return &multiHandler{handlers: next}
+}
+
+func (m *multiHandler) WithGroup(name string) slog.Handler {
+	next := make([]slog.Handler, len(m.handlers))
+	for i, h := range m.handlers {
+		next[i] = h.WithGroup(name)
+	}
+	return &multiHandler{handlers: next}
+}
+
+// ringHandler is a slog.Handler that appends every record it sees to a
+// shared ringBuffer instead of writing it anywhere, so Recent(n) can
+// inspect recent activity without tailing a file.
+type ringHandler struct {
+	buf    *ringBuffer
+	attrs  []slog.Attr
+	groups []string
+}
+
+func (h *ringHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *ringHandler) Handle(_ context.Context, r slog.Record) error {
+	attrs := make(map[string]any, r.NumAttrs()+len(h.attrs))
+	for _, a := range h.attrs {
+		attrs[a.Key] = a.Value.Any()
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value.Any()
+		return true
+	})
+	h.buf.add(Entry{Time: r.Time, Level: r.Level, Message: r.Message, Attrs: attrs})
+	return nil
+}
+
+func (h *ringHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &clone
+}
+
+func (h *ringHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.groups = append(append([]string{}, h.groups...), name)
+	return &clone
+}
+
+// ringBuffer is a fixed-size circular buffer of log entries.
+type ringBuffer struct {
+	mu      sync.Mutex
+	entries []Entry
+	pos     int
+	full    bool
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{entries: make([]Entry, size)}
+}
+
+func (b *ringBuffer) add(e Entry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries[b.pos] = e
+	b.pos = (b.pos + 1) % len(b.entries)
+	if b.pos == 0 {
+		b.full = true
+	}
+}
+
+func (b *ringBuffer) snapshot(n int) []Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var all []Entry
+	if b.full {
+		all = append(all, b.entries[b.pos:]...)
+		all = append(all, b.entries[:b.pos]...)
+	} else {
+		all = append(all, b.entries[:b.pos]...)
+	}
+	if n <= 0 || n >= len(all) {
+		return all
+	}
+	return all[len(all)-n:]
+}
@@ -1,53 +1,171 @@
+// Package logging is the leveled, structured logger shared across the
+// bridge. It fans every record out to a human-readable stderr sink, an
+// in-memory ring buffer inspectable via Recent, and optionally a
+// JSON-lines file sink configured with SetFileSink.
 package logging
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
+	"runtime/debug"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/demo-tools-bridge/pkg/config"
 )
 
-func Info(msg string, args ...any) {
-	write("INFO", msg, args...)
+// defaultRingSize bounds how many recent log entries Recent can return.
+const defaultRingSize = 500
+
+// Entry is a single log record captured by the ring buffer sink.
+type Entry struct {
+	Time    time.Time      `json:"time"`
+	Level   slog.Level     `json:"level"`
+	Message string         `json:"message"`
+	Attrs   map[string]any `json:"attrs,omitempty"`
 }
 
-func Debug(msg string, args ...any) {
-	write("DEBUG", msg, args...)
+type ctxKey struct{}
+
+var (
+	mu          sync.RWMutex
+	textHandler slog.Handler
+	ring        = newRingBuffer(defaultRingSize)
+	ringHdlr    = &ringHandler{buf: ring}
+	fileHandler slog.Handler
+	fileCloser  func() error
+	base        *slog.Logger
+)
+
+func init() {
+	textHandler = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{AddSource: true, Level: dynamicLevel{}})
+	rebuildLocked()
+}
+
+// dynamicLevel defers every Enabled() check to minLevel(), so the stderr
+// and file handlers track Config.DebugLSP/LogLevel as it changes instead
+// of freezing in whatever level was in effect when the handler was built.
+// Without this both handlers default to slog.LevelInfo and silently drop
+// Debug records even once logAt has decided they should be logged.
+type dynamicLevel struct{}
+
+func (dynamicLevel) Level() slog.Level { return minLevel() }
+
+func rebuildLocked() {
+	handlers := []slog.Handler{textHandler, ringHdlr}
+	if fileHandler != nil {
+		handlers = append(handlers, fileHandler)
+	}
+	base = slog.New(&multiHandler{handlers: handlers})
 }
 
-func Warn(msg string, args ...any) {
-	write("WARN", msg, args...)
+// SetFileSink routes a copy of every log record, as JSON lines, to path in
+// addition to the existing sinks. It replaces any previously configured
+// file sink.
+func SetFileSink(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if fileCloser != nil {
+		_ = fileCloser()
+	}
+	fileHandler = slog.NewJSONHandler(f, &slog.HandlerOptions{Level: dynamicLevel{}})
+	fileCloser = f.Close
+	rebuildLocked()
+	return nil
 }
 
-func Error(msg string, args ...any) {
-	write("ERROR", msg, args...)
+// Recent returns up to n of the most recently logged entries, oldest
+// first. n<=0 returns everything still held in the ring buffer.
+func Recent(n int) []Entry {
+	return ring.snapshot(n)
 }
 
-func ErrorPersist(msg string, args ...any) {
-	write("ERROR", msg, args...)
+// With returns a context that carries a logger pre-populated with attrs
+// (alternating key/value pairs, same convention as Info/Debug/Warn/Error).
+// Logging calls made with this context via the *Context variants below
+// include those attrs on every record, so e.g. an LSP server name attached
+// once can be used to filter DebugLSP output per-server.
+func With(ctx context.Context, attrs ...any) context.Context {
+	return context.WithValue(ctx, ctxKey{}, loggerFromContext(ctx).With(attrs...))
 }
 
-func RecoverPanic(scope string, onRecover func()) {
-	if r := recover(); r != nil {
-		write("PANIC", scope, "error", r)
-		if onRecover != nil {
-			onRecover()
+func loggerFromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok && l != nil {
+			return l
 		}
 	}
+	mu.RLock()
+	defer mu.RUnlock()
+	return base
+}
+
+func minLevel() slog.Level {
+	cfg := config.Get()
+	if cfg.DebugLSP {
+		return slog.LevelDebug
+	}
+	switch strings.ToLower(cfg.LogLevel) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+func logAt(ctx context.Context, level slog.Level, msg string, args ...any) {
+	if level < minLevel() {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	loggerFromContext(ctx).Log(ctx, level, msg, args...)
+}
+
+// Info/Debug/Warn/Error/ErrorPersist are thin wrappers kept for existing
+// call sites; they log against context.Background(). Prefer the *Context
+// variants when a request- or server-scoped logger from With is available.
+func Info(msg string, args ...any)  { logAt(context.Background(), slog.LevelInfo, msg, args...) }
+func Debug(msg string, args ...any) { logAt(context.Background(), slog.LevelDebug, msg, args...) }
+func Warn(msg string, args ...any)  { logAt(context.Background(), slog.LevelWarn, msg, args...) }
+func Error(msg string, args ...any) { logAt(context.Background(), slog.LevelError, msg, args...) }
+
+func ErrorPersist(msg string, args ...any) { Error(msg, args...) }
+
+func InfoContext(ctx context.Context, msg string, args ...any) {
+	logAt(ctx, slog.LevelInfo, msg, args...)
+}
+func DebugContext(ctx context.Context, msg string, args ...any) {
+	logAt(ctx, slog.LevelDebug, msg, args...)
+}
+func WarnContext(ctx context.Context, msg string, args ...any) {
+	logAt(ctx, slog.LevelWarn, msg, args...)
+}
+func ErrorContext(ctx context.Context, msg string, args ...any) {
+	logAt(ctx, slog.LevelError, msg, args...)
 }
 
-func write(level string, msg string, args ...any) {
-	var b strings.Builder
-	b.WriteString(level)
-	b.WriteString(": ")
-	b.WriteString(msg)
-	if len(args) > 0 {
-		for i := 0; i < len(args); i += 2 {
-			if i+1 >= len(args) {
-				b.WriteString(fmt.Sprintf(" %v", args[i]))
-				break
-			}
-			b.WriteString(fmt.Sprintf(" %v=%v", args[i], args[i+1]))
+// RecoverPanic recovers a panic in scope, logging it with a stack trace
+// attribute, and then runs onRecover if provided.
+func RecoverPanic(scope string, onRecover func()) {
+	if r := recover(); r != nil {
+		logAt(context.Background(), slog.LevelError, "panic recovered",
+			"scope", scope, "error", r, "stack", string(debug.Stack()))
+		if onRecover != nil {
+			onRecover()
 		}
 	}
-	_, _ = fmt.Fprintln(os.Stderr, b.String())
 }
@@ -0,0 +1,191 @@
+package lsp
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+
+	"github.com/example/demo-tools-bridge/pkg/logging"
+)
+
+// sessionKey identifies one open document as far as reference counting is
+// concerned: a single client's view of a single URI.
+type sessionKey struct {
+	client *Client
+	uri    string
+}
+
+// sharedDoc is the reference-counted state behind every DocumentSession for
+// a given (client, uri) pair. Holding its mutex across the OpenFile call
+// in Acquire is what coalesces concurrent opens: a second Acquire for the
+// same document simply waits for the first one's didOpen to finish, then
+// joins it instead of sending its own. Release sends CloseFile only after
+// releasing the mutex.
+type sharedDoc struct {
+	mu       sync.Mutex
+	refCount int
+	version  int
+	hash     [32]byte
+	hasHash  bool
+	// torndown is set once a Release has decided this doc's refCount
+	// reached zero and removed it from sessions, while still holding mu.
+	// A concurrent Acquire that already looked the doc up before the
+	// removal checks this after taking mu and retries instead of reusing
+	// a doc that's about to (or already did) send didClose.
+	torndown bool
+}
+
+var (
+	sessionsMu sync.Mutex
+	sessions   = map[sessionKey]*sharedDoc{}
+)
+
+// DocumentSession is one tool call's hold on an LSP-open document. Several
+// sessions for the same client and URI can be outstanding at once; the
+// document is only didOpen'd when the first one is acquired and didClose'd
+// when the last one is released.
+type DocumentSession struct {
+	client *Client
+	uri    string
+	ctx    context.Context
+	doc    *sharedDoc
+}
+
+// Acquire opens uri for editing if it isn't already open on this client, or
+// joins the existing open document and increments its reference count. The
+// returned session's Release must be called exactly once, typically via
+// defer, once the caller is done with the document.
+func (c *Client) Acquire(ctx context.Context, uri string) (*DocumentSession, error) {
+	key := sessionKey{client: c, uri: uri}
+
+	for {
+		sessionsMu.Lock()
+		doc, ok := sessions[key]
+		if !ok {
+			doc = &sharedDoc{}
+			sessions[key] = doc
+		}
+		sessionsMu.Unlock()
+
+		doc.mu.Lock()
+		if doc.torndown {
+			// Release for this doc ran (or is running) the delete-and-close
+			// sequence under sessionsMu below; that instance can never be
+			// reused. Drop it and retry so we either join a fresher doc a
+			// concurrent Acquire already installed, or install our own.
+			doc.mu.Unlock()
+			continue
+		}
+		if doc.refCount == 0 {
+			if err := c.OpenFile(ctx, uri); err != nil {
+				doc.mu.Unlock()
+				return nil, fmt.Errorf("acquiring session for %s: %w", uri, err)
+			}
+			doc.version = 1
+			doc.hasHash = false
+		}
+		doc.refCount++
+		doc.mu.Unlock()
+
+		return &DocumentSession{client: c, uri: uri, ctx: ctx, doc: doc}, nil
+	}
+}
+
+// Release decrements the session's reference count, didClosing the document
+// once no sessions remain. Close errors are logged rather than returned,
+// since by the time Release runs (usually deferred) callers have no
+// meaningful way to act on them.
+func (s *DocumentSession) Release() {
+	// sessionsMu is held across the whole decrement-check-delete sequence,
+	// with doc.mu nested inside it, so a concurrent Acquire can't look up
+	// this doc from sessions, find it not yet deleted, and start reusing
+	// it in the gap between the refcount hitting zero and the map entry
+	// actually being removed. Acquire takes the locks in the same order
+	// and rechecks doc.torndown once it has doc.mu, so it either sees
+	// torndown set and retries (if it was already past the sessionsMu
+	// lookup) or waits behind sessionsMu and observes the deletion.
+	sessionsMu.Lock()
+	s.doc.mu.Lock()
+	s.doc.refCount--
+	last := s.doc.refCount <= 0
+	if last {
+		s.doc.torndown = true
+		delete(sessions, sessionKey{client: s.client, uri: s.uri})
+	}
+	s.doc.mu.Unlock()
+	sessionsMu.Unlock()
+	if !last {
+		return
+	}
+
+	if err := s.client.CloseFile(s.ctx, s.uri); err != nil {
+		logging.ErrorContext(s.ctx, "failed to close LSP document", "uri", s.uri, "error", err)
+	}
+}
+
+// Sync sends didChange for content, but only if its hash differs from the
+// last content sent through this (or a sibling) session for the same
+// document, and bumps the shared document version each time it does. This
+// is what lets several tools share one open document without each one
+// re-sending the full file on every call.
+func (s *DocumentSession) Sync(ctx context.Context, content []byte) error {
+	hash := sha256.Sum256(content)
+
+	s.doc.mu.Lock()
+	if s.doc.hasHash && s.doc.hash == hash {
+		s.doc.mu.Unlock()
+		return nil
+	}
+	s.doc.hash = hash
+	s.doc.hasHash = true
+	s.doc.version++
+	s.doc.mu.Unlock()
+
+	return s.client.NotifyChange(ctx, s.uri)
+}
+
+// Version returns the shared document version: 1 once the document is
+// opened, plus one for each time Sync has actually forwarded a didChange.
+func (s *DocumentSession) Version() int {
+	s.doc.mu.Lock()
+	defer s.doc.mu.Unlock()
+	return s.doc.version
+}
+
+// SessionManager gives every tool that opens LSP documents a single shared
+// view of the attached clients, so the reference counts Acquire/Release
+// maintain stay consistent no matter which tool instance opened or closed a
+// file. Registry hands this out in place of the raw client map.
+type SessionManager struct {
+	clients map[string]*Client
+}
+
+// NewSessionManager wraps clients for sharing across tool instances.
+func NewSessionManager(clients map[string]*Client) *SessionManager {
+	return &SessionManager{clients: clients}
+}
+
+// Clients returns the underlying name-to-client map, for callers that need
+// to query every attached client directly rather than acquire a session.
+func (m *SessionManager) Clients() map[string]*Client {
+	return m.clients
+}
+
+// Acquire opens uri on every attached client, returning one DocumentSession
+// per client name. If any client fails to open the file, the sessions
+// already acquired are released before the error is returned.
+func (m *SessionManager) Acquire(ctx context.Context, uri string) (map[string]*DocumentSession, error) {
+	result := make(map[string]*DocumentSession, len(m.clients))
+	for name, client := range m.clients {
+		sess, err := client.Acquire(ctx, uri)
+		if err != nil {
+			for _, s := range result {
+				s.Release()
+			}
+			return nil, fmt.Errorf("acquiring %s on client %s: %w", uri, name, err)
+		}
+		result[name] = sess
+	}
+	return result, nil
+}
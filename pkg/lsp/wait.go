@@ -0,0 +1,139 @@
+package lsp
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/example/demo-tools-bridge/pkg/lsp/protocol"
+)
+
+// diagWaitKey identifies one (client, uri) pair for the purposes of
+// waiting on publishDiagnostics notifications, mirroring sessionKey.
+type diagWaitKey struct {
+	client *Client
+	uri    protocol.DocumentURI
+}
+
+// diagWaitState tracks the most recent textDocument/publishDiagnostics
+// notification for one (client, uri) pair. notify is closed and replaced
+// every time a new notification arrives, so any number of waiters can
+// select on it without missing a wakeup, the same role a sync.Cond would
+// play here.
+type diagWaitState struct {
+	mu     sync.Mutex
+	last   time.Time
+	notify chan struct{}
+}
+
+var (
+	diagWaitsMu sync.Mutex
+	diagWaits   = map[diagWaitKey]*diagWaitState{}
+)
+
+func diagWaitFor(client *Client, uri protocol.DocumentURI) *diagWaitState {
+	key := diagWaitKey{client: client, uri: uri}
+
+	diagWaitsMu.Lock()
+	defer diagWaitsMu.Unlock()
+	st, ok := diagWaits[key]
+	if !ok {
+		st = &diagWaitState{notify: make(chan struct{})}
+		diagWaits[key] = st
+	}
+	return st
+}
+
+// notePublishDiagnostics records that a textDocument/publishDiagnostics
+// notification for uri has just arrived on c, waking any WaitForDiagnostics
+// call currently waiting on it. Nothing in this snapshot's notification
+// dispatch calls this yet, since the dispatch loop itself isn't part of
+// the tree; WaitForDiagnostics below calls it itself whenever its own
+// polling observes a change, so the wait still debounces correctly even
+// without a real dispatch loop wired up, and a future one can call this
+// directly instead of going through polling.
+func (c *Client) notePublishDiagnostics(uri protocol.DocumentURI) {
+	st := diagWaitFor(c, uri)
+
+	st.mu.Lock()
+	st.last = time.Now()
+	close(st.notify)
+	st.notify = make(chan struct{})
+	st.mu.Unlock()
+}
+
+// diagWaitPollInterval bounds how often WaitForDiagnostics re-checks
+// GetDiagnosticsForFile while it has no real publishDiagnostics dispatch
+// to wait on.
+const diagWaitPollInterval = 25 * time.Millisecond
+
+// WaitForDiagnostics blocks until a textDocument/publishDiagnostics
+// notification for uri has arrived during the call and none has followed
+// it for minQuietPeriod, or until maxWait elapses overall, whichever comes
+// first. This mirrors gopls' "wait for
+// quiet" strategy used by its check command: calling GetDiagnosticsForFile
+// immediately after a change notification races the server's own analysis,
+// so callers should wait here first.
+//
+// It polls GetDiagnosticsForFile and treats an observed change as a
+// notification arrival (via notePublishDiagnostics) rather than relying
+// solely on an external caller to report one, so a client with no wired-up
+// dispatch still debounces against real server activity instead of always
+// blocking for the full maxWait.
+func (c *Client) WaitForDiagnostics(ctx context.Context, uri protocol.DocumentURI, minQuietPeriod, maxWait time.Duration) {
+	start := time.Now()
+	deadline := start.Add(maxWait)
+	st := diagWaitFor(c, uri)
+	path := uri.Path()
+
+	last, _ := c.GetDiagnosticsForFile(ctx, path)
+
+	ticker := time.NewTicker(diagWaitPollInterval)
+	defer ticker.Stop()
+
+	for {
+		st.mu.Lock()
+		lastNotified := st.last
+		ch := st.notify
+		st.mu.Unlock()
+		// A notification recorded by an earlier call says nothing about
+		// the change this call is waiting on.
+		if lastNotified.Before(start) {
+			lastNotified = time.Time{}
+		}
+
+		quietFor := time.Since(lastNotified)
+		if !lastNotified.IsZero() && quietFor >= minQuietPeriod {
+			return
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return
+		}
+		wait := remaining
+		if !lastNotified.IsZero() {
+			if w := minQuietPeriod - quietFor; w < remaining {
+				wait = w
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-ch:
+			timer.Stop()
+		case <-timer.C:
+		case <-ticker.C:
+			timer.Stop()
+			current, err := c.GetDiagnosticsForFile(ctx, path)
+			if err == nil && !reflect.DeepEqual(current, last) {
+				last = current
+				c.notePublishDiagnostics(uri)
+			}
+		}
+	}
+}
@@ -10,53 +10,95 @@ import (
 
 	"github.com/bmatcuk/doublestar/v4"
 	"github.com/example/demo-tools-bridge/pkg/config"
+	"github.com/example/demo-tools-bridge/pkg/ignore"
 	"github.com/example/demo-tools-bridge/pkg/logging"
 	"github.com/example/demo-tools-bridge/pkg/lsp"
 	"github.com/example/demo-tools-bridge/pkg/lsp/protocol"
+	"github.com/example/demo-tools-bridge/pkg/selector"
 	"github.com/fsnotify/fsnotify"
 )
 
+// defaultMaxBatchSize forces an early flush once this many distinct URIs
+// are pending, so e.g. a `git clean` touching tens of thousands of files
+// doesn't hold the whole batch in memory until the flush timer fires.
+const defaultMaxBatchSize = 500
+
 type WorkspaceWatcher struct {
 	client         *lsp.Client
 	workspacePath  string
-	debounceTime   time.Duration
-	debounceMap    map[string]*time.Timer
-	debounceMu     sync.Mutex
+	fsWatcher      *fsnotify.Watcher
+	flushInterval  time.Duration
+	maxBatchSize   int
+	pending        map[string]protocol.FileChangeType
+	pendingMu      sync.Mutex
+	flushTimer     *time.Timer
 	registrations  []protocol.FileSystemWatcher
 	registrationMu sync.RWMutex
+	ignore         *ignore.Ignore
+	// sel decides whether a changed path is forwarded to the LSP server at
+	// all, independent of the per-registration glob matching shouldNotify
+	// already does. Defaults to skipping hidden paths; SetSelector lets a
+	// caller add e.g. a size cap or MIME filter.
+	sel selector.SelectFunc
+	// logCtx carries the "component"/"workspace" attributes every log call
+	// made by this watcher should include, so DebugLSP output can be
+	// filtered down to a single workspace watcher among several.
+	logCtx context.Context
 }
 
 func NewWorkspaceWatcher(client *lsp.Client) *WorkspaceWatcher {
 	return &WorkspaceWatcher{
 		client:        client,
-		debounceTime:  300 * time.Millisecond,
-		debounceMap:   make(map[string]*time.Timer),
+		flushInterval: 300 * time.Millisecond,
+		maxBatchSize:  defaultMaxBatchSize,
+		pending:       make(map[string]protocol.FileChangeType),
 		registrations: []protocol.FileSystemWatcher{},
+		sel:           selector.NotHidden(),
+		logCtx:        logging.With(context.Background(), "component", "watcher"),
 	}
 }
 
+// SetFlushInterval overrides how long the watcher waits after the last
+// queued change before sending a batched didChangeWatchedFiles.
+func (w *WorkspaceWatcher) SetFlushInterval(d time.Duration) {
+	w.flushInterval = d
+}
+
+// SetMaxBatchSize overrides how many pending changes force an early flush.
+func (w *WorkspaceWatcher) SetMaxBatchSize(n int) {
+	w.maxBatchSize = n
+}
+
+// SetSelector overrides which changed paths are eligible to be forwarded to
+// the LSP server at all, e.g. selector.All(selector.NotHidden(),
+// selector.BySize(maxSize)) to also drop oversized files. Deleted paths are
+// always passed through sel with a nil os.FileInfo.
+func (w *WorkspaceWatcher) SetSelector(sel selector.SelectFunc) {
+	w.sel = sel
+}
+
 func (w *WorkspaceWatcher) AddRegistrations(ctx context.Context, id string, watchers []protocol.FileSystemWatcher) {
 	cnf := config.Get()
-	logging.Debug("Adding file watcher registrations")
+	logging.DebugContext(w.logCtx, "Adding file watcher registrations")
 	w.registrationMu.Lock()
 	defer w.registrationMu.Unlock()
 	w.registrations = append(w.registrations, watchers...)
 	if cnf.DebugLSP {
-		logging.Debug("Adding file watcher registrations", "id", id, "watchers", len(watchers), "total", len(w.registrations))
+		logging.DebugContext(w.logCtx, "Adding file watcher registrations", "id", id, "watchers", len(watchers), "total", len(w.registrations))
 		for i, watcher := range watchers {
-			logging.Debug("Registration", "index", i+1)
+			logging.DebugContext(w.logCtx, "Registration", "index", i+1)
 			switch v := watcher.GlobPattern.Value.(type) {
 			case string:
-				logging.Debug("GlobPattern", "pattern", v)
+				logging.DebugContext(w.logCtx, "GlobPattern", "pattern", v)
 			case protocol.RelativePattern:
-				logging.Debug("GlobPattern", "pattern", v.Pattern)
+				logging.DebugContext(w.logCtx, "GlobPattern", "pattern", v.Pattern)
 				switch u := v.BaseURI.Value.(type) {
 				case string:
-					logging.Debug("BaseURI", "baseURI", u)
+					logging.DebugContext(w.logCtx, "BaseURI", "baseURI", u)
 				case protocol.DocumentUri:
-					logging.Debug("BaseURI", "baseURI", u)
+					logging.DebugContext(w.logCtx, "BaseURI", "baseURI", u)
 				default:
-					logging.Debug("BaseURI", "baseURI", u)
+					logging.DebugContext(w.logCtx, "BaseURI", "baseURI", u)
 				}
 			}
 		}
@@ -65,31 +107,25 @@ func (w *WorkspaceWatcher) AddRegistrations(ctx context.Context, id string, watc
 
 func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath string) {
 	w.workspacePath = workspacePath
+	w.logCtx = logging.With(w.logCtx, "workspace", workspacePath)
+	ig, err := ignore.New(workspacePath)
+	if err != nil {
+		logging.ErrorContext(w.logCtx, "Failed to load ignore rules", "error", err)
+		ig = nil
+	}
+	w.ignore = ig
 	lsp.RegisterFileWatchHandler(func(id string, watchers []protocol.FileSystemWatcher) {
 		w.AddRegistrations(ctx, id, watchers)
 	})
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		logging.Error("Failed to create file watcher", "error", err)
+		logging.ErrorContext(w.logCtx, "Failed to create file watcher", "error", err)
 		return
 	}
 	defer watcher.Close()
-	err = filepath.Walk(workspacePath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
-		if info.IsDir() {
-			if shouldSkipDirectory(path) {
-				return filepath.SkipDir
-			}
-			if err := watcher.Add(path); err != nil {
-				logging.Error("Failed to watch directory", "path", path, "error", err)
-			}
-		}
-		return nil
-	})
-	if err != nil {
-		logging.Error("Failed to walk workspace", "error", err)
+	w.fsWatcher = watcher
+	if err := w.addSubtree(workspacePath); err != nil {
+		logging.ErrorContext(w.logCtx, "Failed to walk workspace", "error", err)
 		return
 	}
 	for {
@@ -105,7 +141,7 @@ func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath str
 			if !ok {
 				return
 			}
-			logging.Error("Watcher error", "error", err)
+			logging.ErrorContext(w.logCtx, "Watcher error", "error", err)
 		}
 	}
 }
@@ -114,67 +150,156 @@ func (w *WorkspaceWatcher) handleEvent(ctx context.Context, event fsnotify.Event
 	path := event.Name
 	info, err := os.Stat(path)
 	if err == nil && info.IsDir() && event.Op&fsnotify.Create == fsnotify.Create {
-		if shouldSkipDirectory(path) {
+		if w.shouldSkipDirectory(path) {
 			return
 		}
+		// A whole subtree can appear in one Create event (branch switch,
+		// `mkdir -p`, a generator writing a new package), and fsnotify
+		// never reports the directories created underneath it, so walk
+		// and watch them now rather than silently missing their events.
+		if err := w.addSubtree(path); err != nil {
+			logging.ErrorContext(w.logCtx, "Failed to watch new subtree", "path", path, "error", err)
+		}
+	}
+	if w.sel != nil && !w.sel(path, info) {
+		return
 	}
 	if event.Op&fsnotify.Create == fsnotify.Create {
-		w.handleFileEvent(ctx, "file://"+path, protocol.FileChangeType(protocol.Created))
+		w.queueEvent(ctx, "file://"+path, protocol.FileChangeType(protocol.Created))
 	}
 	if event.Op&fsnotify.Write == fsnotify.Write {
-		w.debounceEvent(ctx, "file://"+path, protocol.FileChangeType(protocol.Changed))
+		w.queueEvent(ctx, "file://"+path, protocol.FileChangeType(protocol.Changed))
 	}
 	if event.Op&fsnotify.Remove == fsnotify.Remove {
-		w.handleFileEvent(ctx, "file://"+path, protocol.FileChangeType(protocol.Deleted))
+		w.queueEvent(ctx, "file://"+path, protocol.FileChangeType(protocol.Deleted))
 	}
 	if event.Op&fsnotify.Rename == fsnotify.Rename {
-		w.handleFileEvent(ctx, "file://"+path, protocol.FileChangeType(protocol.Deleted))
+		w.queueEvent(ctx, "file://"+path, protocol.FileChangeType(protocol.Deleted))
 	}
 }
 
-func (w *WorkspaceWatcher) debounceEvent(ctx context.Context, uri string, changeType protocol.FileChangeType) {
-	w.debounceMu.Lock()
-	defer w.debounceMu.Unlock()
-	if timer, ok := w.debounceMap[uri]; ok {
-		timer.Stop()
-	}
-	w.debounceMap[uri] = time.AfterFunc(w.debounceTime, func() {
-		w.handleFileEvent(ctx, uri, changeType)
-		w.debounceMu.Lock()
-		delete(w.debounceMap, uri)
-		w.debounceMu.Unlock()
+// addSubtree walks root (including root itself) and registers every
+// directory under it, skipping ones shouldSkipDirectory rejects, with the
+// underlying fsnotify watcher.
+func (w *WorkspaceWatcher) addSubtree(root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if w.shouldSkipDirectory(path) {
+			return filepath.SkipDir
+		}
+		if err := w.fsWatcher.Add(path); err != nil {
+			logging.ErrorContext(w.logCtx, "Failed to watch directory", "path", path, "error", err)
+		}
+		return nil
 	})
 }
 
-func (w *WorkspaceWatcher) handleFileEvent(ctx context.Context, uri string, changeType protocol.FileChangeType) {
-	filePath := uri[7:]
-	if changeType == protocol.FileChangeType(protocol.Deleted) {
-		w.client.ClearDiagnosticsForURI(protocol.DocumentUri(uri))
-	} else if changeType == protocol.FileChangeType(protocol.Changed) && w.client.IsFileOpen(filePath) {
-		err := w.client.NotifyChange(ctx, filePath)
-		if err != nil {
-			logging.Error("Error notifying change", "error", err)
+// queueEvent records uri's change, collapsing it with any still-pending
+// change for the same uri (e.g. Create+Write collapses to Create, and
+// Create+Delete cancels out entirely), then schedules or forces a flush.
+func (w *WorkspaceWatcher) queueEvent(ctx context.Context, uri string, changeType protocol.FileChangeType) {
+	w.pendingMu.Lock()
+	if existing, ok := w.pending[uri]; ok {
+		merged, drop := collapseChangeType(existing, changeType)
+		if drop {
+			delete(w.pending, uri)
+		} else {
+			w.pending[uri] = merged
 		}
-		return
+	} else {
+		w.pending[uri] = changeType
 	}
-	if err := w.notifyFileEvent(ctx, uri, changeType); err != nil {
-		logging.Error("Error notifying LSP server about file event", "error", err)
+	forceFlush := len(w.pending) >= w.maxBatchSize
+	if !forceFlush {
+		if w.flushTimer != nil {
+			w.flushTimer.Stop()
+		}
+		w.flushTimer = time.AfterFunc(w.flushInterval, func() { w.flush(ctx) })
+	}
+	w.pendingMu.Unlock()
+
+	if forceFlush {
+		w.flush(ctx)
 	}
 }
 
-func (w *WorkspaceWatcher) notifyFileEvent(ctx context.Context, uri string, changeType protocol.FileChangeType) error {
-	if !w.shouldNotify(uri) {
-		return nil
+// collapseChangeType merges a newly observed change into one already
+// pending for the same URI, mirroring how the LSP spec expects a single
+// didChangeWatchedFiles burst to be interpreted: a file created and then
+// deleted within the same batch never existed as far as the server needs
+// to know, and a freshly created file that is then written is still just
+// a Create.
+func collapseChangeType(existing, next protocol.FileChangeType) (merged protocol.FileChangeType, drop bool) {
+	created := protocol.FileChangeType(protocol.Created)
+	changed := protocol.FileChangeType(protocol.Changed)
+	deleted := protocol.FileChangeType(protocol.Deleted)
+
+	switch existing {
+	case created:
+		if next == deleted {
+			return 0, true
+		}
+		return created, false
+	case deleted:
+		if next == created {
+			return created, false
+		}
+		return next, false
+	default: // changed
+		return next, false
 	}
-	params := protocol.DidChangeWatchedFilesParams{
-		Changes: []protocol.FileEvent{
-			{
-				URI:  protocol.DocumentUri(uri),
-				Type: changeType,
-			},
-		},
+}
+
+// flush sends every pending change as one batched didChangeWatchedFiles
+// notification. Changes to files already open in the LSP server are sent
+// as didChange instead, and deletions clear cached diagnostics, matching
+// the previous per-event behavior.
+func (w *WorkspaceWatcher) flush(ctx context.Context) {
+	w.pendingMu.Lock()
+	if len(w.pending) == 0 {
+		w.pendingMu.Unlock()
+		return
+	}
+	pending := w.pending
+	w.pending = make(map[string]protocol.FileChangeType)
+	if w.flushTimer != nil {
+		w.flushTimer.Stop()
+		w.flushTimer = nil
+	}
+	w.pendingMu.Unlock()
+
+	changed := protocol.FileChangeType(protocol.Changed)
+	deleted := protocol.FileChangeType(protocol.Deleted)
+
+	changes := make([]protocol.FileEvent, 0, len(pending))
+	for uri, changeType := range pending {
+		filePath := strings.TrimPrefix(uri, "file://")
+		if changeType == deleted {
+			w.client.ClearDiagnosticsForURI(protocol.DocumentUri(uri))
+		} else if changeType == changed && w.client.IsFileOpen(filePath) {
+			if err := w.client.NotifyChange(ctx, filePath); err != nil {
+				logging.ErrorContext(w.logCtx, "Error notifying change", "error", err)
+			}
+			continue
+		}
+		if !w.shouldNotify(uri) {
+			continue
+		}
+		changes = append(changes, protocol.FileEvent{URI: protocol.DocumentUri(uri), Type: changeType})
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	params := protocol.DidChangeWatchedFilesParams{Changes: changes}
+	if err := w.client.DidChangeWatchedFiles(ctx, params); err != nil {
+		logging.ErrorContext(w.logCtx, "Error notifying LSP server about file events", "error", err)
 	}
-	return w.client.DidChangeWatchedFiles(ctx, params)
 }
 
 func (w *WorkspaceWatcher) shouldNotify(uri string) bool {
@@ -191,7 +316,7 @@ func (w *WorkspaceWatcher) shouldNotify(uri string) bool {
 func (w *WorkspaceWatcher) matchesWatcher(uri string, watcher protocol.FileSystemWatcher) bool {
 	switch v := watcher.GlobPattern.Value.(type) {
 	case string:
-		return matchGlobPattern(v, uri, w.workspacePath)
+		return w.matchGlobPattern(v, uri, w.workspacePath)
 	case protocol.RelativePattern:
 		base := ""
 		switch u := v.BaseURI.Value.(type) {
@@ -200,12 +325,12 @@ func (w *WorkspaceWatcher) matchesWatcher(uri string, watcher protocol.FileSyste
 		case protocol.DocumentUri:
 			base = string(u)
 		}
-		return matchGlobPattern(v.Pattern, uri, strings.TrimPrefix(base, "file://"))
+		return w.matchGlobPattern(v.Pattern, uri, strings.TrimPrefix(base, "file://"))
 	}
 	return false
 }
 
-func matchGlobPattern(pattern string, uri string, basePath string) bool {
+func (w *WorkspaceWatcher) matchGlobPattern(pattern string, uri string, basePath string) bool {
 	path := strings.TrimPrefix(uri, "file://")
 	if basePath != "" && !strings.HasPrefix(path, basePath) {
 		return false
@@ -219,13 +344,22 @@ func matchGlobPattern(pattern string, uri string, basePath string) bool {
 	}
 	ok, err := doublestar.Match(pattern, relPath)
 	if err != nil {
-		logging.Error("Error matching glob pattern", "pattern", pattern, "path", relPath, "error", err)
+		logging.ErrorContext(w.logCtx, "Error matching glob pattern", "pattern", pattern, "path", relPath, "error", err)
 		return false
 	}
 	return ok
 }
 
-func shouldSkipDirectory(path string) bool {
+// shouldSkipDirectory reports whether path should not be watched. When the
+// workspace has its own .gitignore/.ignore/.lspignore rules, those decide;
+// the hardcoded skip list below is only a fallback for workspaces that
+// don't define any ignore files of their own.
+func (w *WorkspaceWatcher) shouldSkipDirectory(path string) bool {
+	if w.ignore != nil && w.ignore.HasPatterns() {
+		ignored, _ := w.ignore.Match(path)
+		return ignored
+	}
+
 	base := filepath.Base(path)
 	if base != "." && strings.HasPrefix(base, ".") {
 		return true
@@ -0,0 +1,161 @@
+// Package selector provides composable predicates for deciding whether a
+// filesystem path should be considered by a file-scanning tool (grep's
+// regex fallback, the glob tool, the workspace watcher). Built-in
+// predicates and the All/Any/Not combinators let callers assemble a
+// pipeline (e.g. NotHidden + BySize + NotBinary) without each tool
+// re-implementing its own filtering rules.
+package selector
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SelectFunc reports whether path, with its already-stat'd fi, should be
+// selected. fi may be nil when a caller doesn't have one on hand (e.g.
+// checking a path before it's created); predicates that don't need file
+// metadata should still behave sensibly in that case.
+type SelectFunc func(path string, fi os.FileInfo) bool
+
+// All returns a SelectFunc selecting a path only when every fn does. An
+// empty All selects everything.
+func All(fns ...SelectFunc) SelectFunc {
+	return func(path string, fi os.FileInfo) bool {
+		for _, fn := range fns {
+			if !fn(path, fi) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Any returns a SelectFunc selecting a path when at least one fn does. An
+// empty Any selects nothing.
+func Any(fns ...SelectFunc) SelectFunc {
+	return func(path string, fi os.FileInfo) bool {
+		for _, fn := range fns {
+			if fn(path, fi) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not inverts fn.
+func Not(fn SelectFunc) SelectFunc {
+	return func(path string, fi os.FileInfo) bool {
+		return !fn(path, fi)
+	}
+}
+
+// ByGlob selects paths whose base name matches pattern (filepath.Match
+// syntax, e.g. "*.go"). An invalid pattern selects nothing.
+func ByGlob(pattern string) SelectFunc {
+	return func(path string, fi os.FileInfo) bool {
+		ok, err := filepath.Match(pattern, filepath.Base(path))
+		return err == nil && ok
+	}
+}
+
+// BySize selects regular files no larger than maxBytes. Directories, and
+// paths with no fi, are always selected since a size limit governs file
+// content rather than tree traversal.
+func BySize(maxBytes int64) SelectFunc {
+	return func(path string, fi os.FileInfo) bool {
+		if fi == nil || fi.IsDir() {
+			return true
+		}
+		return fi.Size() <= maxBytes
+	}
+}
+
+// ByModTimeAfter selects files modified at or after t. Directories, and
+// paths with no fi, are always selected.
+func ByModTimeAfter(t time.Time) SelectFunc {
+	return func(path string, fi os.FileInfo) bool {
+		if fi == nil || fi.IsDir() {
+			return true
+		}
+		return !fi.ModTime().Before(t)
+	}
+}
+
+// ByMIME selects regular files whose sniffed content type (via
+// http.DetectContentType) starts with one of prefixes, e.g. "text/" or
+// "image/png". Directories are always selected; a file that can't be read
+// is not.
+func ByMIME(prefixes ...string) SelectFunc {
+	return func(path string, fi os.FileInfo) bool {
+		if fi == nil || fi.IsDir() {
+			return true
+		}
+		contentType, err := detectContentType(path)
+		if err != nil {
+			return false
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(contentType, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// NotBinary selects files that don't look like binary data, using the same
+// null-byte-in-the-first-8KiB heuristic the grep fallback has always used.
+func NotBinary() SelectFunc {
+	return func(path string, fi os.FileInfo) bool {
+		if fi == nil || fi.IsDir() {
+			return true
+		}
+		isBinary, err := looksBinary(path)
+		return err == nil && !isBinary
+	}
+}
+
+// NotHidden selects paths whose base name doesn't start with "." (other
+// than "." itself).
+func NotHidden() SelectFunc {
+	return func(path string, fi os.FileInfo) bool {
+		base := filepath.Base(path)
+		return base == "." || !strings.HasPrefix(base, ".")
+	}
+}
+
+func detectContentType(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, err := f.Read(buf)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+func looksBinary(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 8192)
+	n, err := f.Read(buf)
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	return bytes.IndexByte(buf[:n], 0) >= 0, nil
+}
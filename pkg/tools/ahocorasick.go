@@ -0,0 +1,146 @@
+package tools
+
+import "sort"
+
+// acNode is one state in an Aho-Corasick trie: a byte-keyed transition
+// table (the "goto" edges), a failure link to the longest proper suffix of
+// this state's path that is also a prefix of some pattern, and the set of
+// pattern indices that terminate at or through this state (merged with the
+// failure link's output at build time, so matching never has to walk the
+// failure chain to collect outputs).
+type acNode struct {
+	children map[byte]*acNode
+	fail     *acNode
+	output   []int
+}
+
+// ahoCorasick matches a fixed set of literal patterns against a byte stream
+// in a single pass, used by the grep fallback when fixed_strings is set so
+// searching for dozens of identifiers at once stays linear in input size
+// instead of linear in input size times pattern count.
+type ahoCorasick struct {
+	root *acNode
+}
+
+// newAhoCorasick builds the trie over patterns, then computes failure links
+// with a BFS over the trie (each node's failure pointer is the child on the
+// same byte of the first node along its parent's failure chain that has
+// one, or the root if none does).
+func newAhoCorasick(patterns []string) *ahoCorasick {
+	root := &acNode{children: map[byte]*acNode{}}
+	for i, p := range patterns {
+		node := root
+		for j := 0; j < len(p); j++ {
+			b := p[j]
+			child, ok := node.children[b]
+			if !ok {
+				child = &acNode{children: map[byte]*acNode{}}
+				node.children[b] = child
+			}
+			node = child
+		}
+		node.output = append(node.output, i)
+	}
+
+	queue := make([]*acNode, 0, len(root.children))
+	for _, child := range root.children {
+		child.fail = root
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for b, child := range node.children {
+			queue = append(queue, child)
+
+			failNode := node.fail
+			for failNode != nil {
+				if next, ok := failNode.children[b]; ok {
+					child.fail = next
+					break
+				}
+				failNode = failNode.fail
+			}
+			if child.fail == nil {
+				child.fail = root
+			}
+			child.output = append(child.output, child.fail.output...)
+		}
+	}
+
+	return &ahoCorasick{root: root}
+}
+
+// step follows the goto edge for b from node, falling back through failure
+// links when node has no such edge, per the standard Aho-Corasick scan.
+func (ac *ahoCorasick) step(node *acNode, b byte) *acNode {
+	for node != ac.root {
+		if child, ok := node.children[b]; ok {
+			return child
+		}
+		node = node.fail
+	}
+	if child, ok := ac.root.children[b]; ok {
+		return child
+	}
+	return ac.root
+}
+
+// MatchAny reports whether data contains any of the automaton's patterns.
+func (ac *ahoCorasick) MatchAny(data []byte) bool {
+	node := ac.root
+	for _, b := range data {
+		node = ac.step(node, b)
+		if len(node.output) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns the sorted, de-duplicated indices of every pattern found
+// anywhere in data.
+func (ac *ahoCorasick) Match(data []byte) []int {
+	seen := map[int]bool{}
+	node := ac.root
+	for _, b := range data {
+		node = ac.step(node, b)
+		for _, idx := range node.output {
+			seen[idx] = true
+		}
+	}
+	indices := make([]int, 0, len(seen))
+	for idx := range seen {
+		indices = append(indices, idx)
+	}
+	sort.Ints(indices)
+	return indices
+}
+
+// FirstMatch scans data and returns the end offset (exclusive) and pattern
+// index of the first match encountered, or ok=false if none of the
+// patterns occur anywhere in data.
+func (ac *ahoCorasick) FirstMatch(data []byte) (end int, idx int, ok bool) {
+	node := ac.root
+	for i, b := range data {
+		node = ac.step(node, b)
+		if len(node.output) > 0 {
+			return i + 1, node.output[0], true
+		}
+	}
+	return 0, 0, false
+}
+
+// CountOccurrences counts every position in data where a pattern ends,
+// counting each pattern separately when more than one ends at the same
+// position. This mirrors the "total occurrences" semantics used elsewhere
+// in this package for multiline regex counting.
+func (ac *ahoCorasick) CountOccurrences(data []byte) int {
+	node := ac.root
+	count := 0
+	for _, b := range data {
+		node = ac.step(node, b)
+		count += len(node.output)
+	}
+	return count
+}
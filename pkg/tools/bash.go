@@ -1,13 +1,15 @@
 package tools
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os/exec"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -16,11 +18,28 @@ const (
 	DefaultTimeoutMs = 60 * 1000
 	MaxTimeoutMs     = 10 * 60 * 1000
 	MaxOutputLength  = 30000
+
+	// bashHeadLimit and bashTailLimit split MaxOutputLength between a fixed
+	// preamble (so a command's opening banner survives truncation) and a
+	// rolling tail of the most recent output.
+	bashHeadLimit = 4 * 1024
+	bashTailLimit = MaxOutputLength - bashHeadLimit
+
+	// bashKillGrace is how long killGracefully waits after SIGTERM before
+	// escalating to SIGKILL.
+	bashKillGrace = 5 * time.Second
+
+	// bashFlushInterval paces the intermediate chunks RunStreaming sends
+	// while params.Stream is set.
+	bashFlushInterval = 200 * time.Millisecond
 )
 
 type BashParams struct {
 	Command string `json:"command"`
 	Timeout int    `json:"timeout"`
+	// Stream, when true, asks RunStreaming to send a chunk on an interval
+	// as output arrives instead of only once the command finishes.
+	Stream bool `json:"stream"`
 }
 
 type BashResponseMetadata struct {
@@ -48,18 +67,45 @@ func (b *bashTool) Info() ToolInfo {
 				"type":        "number",
 				"description": "Optional timeout in milliseconds (max 600000)",
 			},
+			"stream": map[string]any{
+				"type":        "boolean",
+				"description": "If true, report output incrementally as it arrives instead of only once the command finishes",
+			},
 		},
 		Required: []string{"command"},
 	}
 }
 
 func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	streaming, err := b.RunStreaming(ctx, call)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	var last ToolResponse
+	for resp := range streaming.Chunks {
+		last = resp
+	}
+	return last, nil
+}
+
+// RunStreaming executes the command with its stdout/stderr piped through a
+// bounded rolling buffer, sending ToolResponse chunks back on the returned
+// channel as it goes. Once the command has started, the channel receives a
+// final chunk with BashResponseMetadata set, then closes; if the command
+// cannot be started, the only chunk is an error response without metadata.
+//
+// The command runs in its own process group (setProcessGroup) so that on
+// cancellation or timeout, killGracefully can reach every process the shell
+// forked rather than just the shell itself - exec.CommandContext's
+// cancellation only reaches the direct child, which leaves orphaned
+// grandchildren behind on Unix.
+func (b *bashTool) RunStreaming(ctx context.Context, call ToolCall) (StreamingToolResponse, error) {
 	var params BashParams
 	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
-		return NewTextErrorResponse("invalid parameters"), nil
+		return StreamingToolResponse{}, fmt.Errorf("invalid parameters: %w", err)
 	}
 	if params.Command == "" {
-		return NewTextErrorResponse("missing command"), nil
+		return StreamingToolResponse{}, fmt.Errorf("missing command")
 	}
 
 	timeout := params.Timeout
@@ -69,64 +115,205 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		timeout = MaxTimeoutMs
 	}
 
-	runCtx := ctx
-	var cancel context.CancelFunc
-	if timeout > 0 {
-		runCtx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Millisecond)
-		defer cancel()
-	}
+	out := make(chan ToolResponse)
+	go b.run(ctx, params, time.Duration(timeout)*time.Millisecond, out)
+	return StreamingToolResponse{Chunks: out}, nil
+}
+
+func (b *bashTool) run(ctx context.Context, params BashParams, timeout time.Duration, out chan<- ToolResponse) {
+	defer close(out)
 
 	startTime := time.Now()
 	cmdName, cmdArgs := shellForCommand(params.Command)
-	cmd := exec.CommandContext(runCtx, cmdName, cmdArgs...)
+	cmd := exec.Command(cmdName, cmdArgs...)
 	cmd.Dir = WorkingDir()
+	setProcessGroup(cmd)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		out <- NewTextErrorResponse(fmt.Sprintf("error starting command: %s", err))
+		return
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		out <- NewTextErrorResponse(fmt.Sprintf("error starting command: %s", err))
+		return
+	}
+	if err := cmd.Start(); err != nil {
+		out <- NewTextErrorResponse(fmt.Sprintf("error starting command: %s", err))
+		return
+	}
+
+	rolling := &rollingOutput{}
+	var pumps sync.WaitGroup
+	pumps.Add(2)
+	go pumpOutput(stdout, rolling, &pumps)
+	go pumpOutput(stderr, rolling, &pumps)
 
-	var stdout bytes.Buffer
-	var stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	done := make(chan error, 1)
+	go func() {
+		pumps.Wait()
+		done <- cmd.Wait()
+	}()
+
+	var ticker *time.Ticker
+	var flush <-chan time.Time
+	if params.Stream {
+		ticker = time.NewTicker(bashFlushInterval)
+		defer ticker.Stop()
+		flush = ticker.C
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var timedOut bool
+	var runErr error
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			runErr = killGracefully(cmd, done)
+			break loop
+		case <-timer.C:
+			timedOut = true
+			runErr = killGracefully(cmd, done)
+			break loop
+		case <-flush:
+			out <- NewTextResponse(rolling.String())
+		case runErr = <-done:
+			break loop
+		}
+	}
 
 	exitCode := 0
-	err := cmd.Run()
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
+	if runErr != nil {
+		var exitErr *exec.ExitError
+		if errors.As(runErr, &exitErr) {
 			exitCode = exitErr.ExitCode()
 		} else {
 			exitCode = -1
 		}
 	}
 
-	outStr := truncateOutput(stdout.String())
-	errStr := truncateOutput(stderr.String())
-	if runCtx.Err() == context.DeadlineExceeded {
-		if errStr != "" {
-			errStr += "\n"
-		}
-		errStr += "Command timed out"
+	result := rolling.String()
+	switch {
+	case timedOut:
+		result = appendStatus(result, "Command timed out")
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result = appendStatus(result, "Command timed out")
+	case ctx.Err() != nil:
+		result = appendStatus(result, "Command canceled")
 	}
-	if exitCode != 0 && errStr == "" {
-		errStr = fmt.Sprintf("Exit code %d", exitCode)
-	}
-
-	result := outStr
-	if errStr != "" {
-		if result != "" {
-			result += "\n"
-		}
-		result += errStr
+	if exitCode != 0 && result == "" {
+		result = fmt.Sprintf("Exit code %d", exitCode)
 	}
 	if result == "" {
 		result = "no output"
 	}
 
-	return WithResponseMetadata(
+	out <- WithResponseMetadata(
 		NewTextResponse(result),
 		BashResponseMetadata{
 			StartTime: startTime.UnixMilli(),
 			EndTime:   time.Now().UnixMilli(),
 			ExitCode:  exitCode,
 		},
-	), nil
+	)
+}
+
+func appendStatus(result, status string) string {
+	if result != "" {
+		result += "\n"
+	}
+	return result + status
+}
+
+// killGracefully sends SIGTERM to cmd's process group and waits up to
+// bashKillGrace for it to exit on its own before escalating to SIGKILL.
+func killGracefully(cmd *exec.Cmd, done <-chan error) error {
+	terminateProcessGroup(cmd, sigTerm)
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(bashKillGrace):
+		terminateProcessGroup(cmd, sigKill)
+		return <-done
+	}
+}
+
+// pumpOutput copies r into rolling until it's exhausted, then reports
+// completion via wg. Running one of these per stream (stdout, stderr) lets
+// both be read concurrently without either blocking the other.
+func pumpOutput(r io.Reader, rolling *rollingOutput, wg *sync.WaitGroup) {
+	defer wg.Done()
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			rolling.Write(buf[:n])
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// rollingOutput is a bounded view of a command's combined stdout/stderr: it
+// keeps the first bashHeadLimit bytes ever written (a command's banner or
+// usage text is often at the very start) plus the most recent bashTailLimit
+// bytes, so memory use stays flat regardless of how much output a
+// long-running command produces.
+type rollingOutput struct {
+	mu    sync.Mutex
+	head  []byte
+	tail  []byte
+	total int64
+}
+
+func (r *rollingOutput) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.total += int64(len(p))
+
+	if len(r.head) < bashHeadLimit {
+		n := bashHeadLimit - len(r.head)
+		if n > len(p) {
+			n = len(p)
+		}
+		r.head = append(r.head, p[:n]...)
+	}
+
+	r.tail = append(r.tail, p...)
+	if len(r.tail) > bashTailLimit {
+		drop := len(r.tail) - bashTailLimit
+		r.tail = append(r.tail[:0:0], r.tail[drop:]...)
+	}
+	return len(p), nil
+}
+
+// String renders what's been captured so far. Output only actually gets
+// dropped once total exceeds the combined head+tail capacity
+// (MaxOutputLength); below that, tail's rolling window is narrower than
+// total but head still holds whatever prefix fell out of it, so the two
+// are spliced back together with no banner and no duplicated bytes rather
+// than claiming a truncation that never happened.
+func (r *rollingOutput) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.total <= bashHeadLimit+bashTailLimit {
+		prefixLen := r.total - int64(len(r.tail))
+		if prefixLen <= 0 {
+			return strings.TrimRight(string(r.tail), "\n")
+		}
+		return strings.TrimRight(string(r.head[:prefixLen])+string(r.tail), "\n")
+	}
+
+	head := strings.TrimRight(string(r.head), "\n")
+	tail := strings.TrimRight(string(r.tail), "\n")
+	return head + "\n...(output truncated)...\n" + tail
 }
 
 func shellForCommand(command string) (string, []string) {
@@ -135,12 +322,3 @@ func shellForCommand(command string) (string, []string) {
 	}
 	return "/bin/sh", []string{"-c", command}
 }
-
-func truncateOutput(text string) string {
-	if len(text) <= MaxOutputLength {
-		return strings.TrimRight(text, "\n")
-	}
-	truncated := text[:MaxOutputLength]
-	truncated = strings.TrimRight(truncated, "\n")
-	return truncated + "\n(output truncated)"
-}
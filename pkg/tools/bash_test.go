@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRollingOutputUnderCombinedBudget(t *testing.T) {
+	r := &rollingOutput{}
+	r.Write([]byte("hello"))
+	r.Write([]byte(" world"))
+
+	got := r.String()
+	want := "hello world"
+	if got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if strings.Contains(got, "truncated") {
+		t.Errorf("String() reported truncation for output under the combined head+tail budget: %q", got)
+	}
+}
+
+func TestRollingOutputExactlyAtCombinedBudget(t *testing.T) {
+	r := &rollingOutput{}
+	r.Write(make([]byte, bashHeadLimit+bashTailLimit))
+
+	if got := r.String(); strings.Contains(got, "truncated") {
+		t.Errorf("String() reported truncation at exactly the combined budget boundary")
+	}
+}
+
+func TestRollingOutputBeyondCombinedBudget(t *testing.T) {
+	r := &rollingOutput{}
+	r.Write([]byte(strings.Repeat("a", bashHeadLimit)))
+	r.Write([]byte(strings.Repeat("b", bashTailLimit+1)))
+
+	got := r.String()
+	if !strings.Contains(got, "truncated") {
+		t.Errorf("String() did not report truncation once total exceeded the combined budget")
+	}
+	if !strings.HasPrefix(got, strings.Repeat("a", bashHeadLimit)) {
+		t.Errorf("String() did not preserve the original head once truncated")
+	}
+	if !strings.HasSuffix(got, strings.Repeat("b", bashTailLimit)) {
+		t.Errorf("String() did not preserve the most recent tail once truncated")
+	}
+}
+
+func TestRollingOutputGapBetweenHeadAndTailIsSpliced(t *testing.T) {
+	r := &rollingOutput{}
+	r.Write([]byte(strings.Repeat("a", bashHeadLimit)))
+	r.Write([]byte(strings.Repeat("b", bashTailLimit-1000)))
+
+	got := r.String()
+	if strings.Contains(got, "truncated") {
+		t.Errorf("String() reported truncation even though total never exceeded the combined budget: %q", got)
+	}
+	want := strings.Repeat("a", bashHeadLimit) + strings.Repeat("b", bashTailLimit-1000)
+	if got != want {
+		t.Errorf("String() did not splice head and tail back together with no gap or duplication")
+	}
+}
@@ -0,0 +1,35 @@
+//go:build !windows
+
+package tools
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// sigTerm and sigKill are the signals killGracefully escalates through; on
+// Unix these are the real SIGTERM/SIGKILL.
+var (
+	sigTerm os.Signal = syscall.SIGTERM
+	sigKill os.Signal = syscall.SIGKILL
+)
+
+// setProcessGroup puts cmd in its own process group so terminateProcessGroup
+// can reach every process the shell forks, not just the shell itself.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// terminateProcessGroup signals cmd's entire process group. The negated PID
+// is the syscall.Kill convention for "the group" rather than one process.
+func terminateProcessGroup(cmd *exec.Cmd, sig os.Signal) {
+	if cmd.Process == nil {
+		return
+	}
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		s = syscall.SIGKILL
+	}
+	_ = syscall.Kill(-cmd.Process.Pid, s)
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package tools
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// sigTerm and sigKill are placeholders on Windows, which has no signal
+// equivalent; terminateProcessGroup below always force-kills regardless of
+// which one is passed.
+var (
+	sigTerm os.Signal = os.Kill
+	sigKill os.Signal = os.Kill
+)
+
+// setProcessGroup is a no-op on Windows: exec.Cmd has no Setpgid concept
+// there, and terminateProcessGroup uses taskkill's process tree instead.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminateProcessGroup force-kills cmd's whole process tree via taskkill,
+// since Windows has no process-group signal to send.
+func terminateProcessGroup(cmd *exec.Cmd, _ os.Signal) {
+	if cmd.Process == nil {
+		return
+	}
+	_ = exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/example/demo-tools-bridge/pkg/lsp"
 	"github.com/example/demo-tools-bridge/pkg/lsp/protocol"
@@ -13,17 +16,68 @@ import (
 
 const DiagnosticsToolName = "diagnostics"
 
+// diagnosticsQuietPeriod and diagnosticsMaxWait bound how long
+// waitForFileDiagnostics gives a language server to settle after a file change
+// before it gives up and reports whatever has arrived so far.
+const (
+	diagnosticsQuietPeriod = 250 * time.Millisecond
+	diagnosticsMaxWait     = 5 * time.Second
+)
+
+// sarifSchemaURI is the canonical SARIF 2.1.0 JSON schema location, used
+// as the $schema field of every SARIF log this tool emits.
+const sarifSchemaURI = "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json"
+
 type DiagnosticsParams struct {
 	FilePath string `json:"file_path"`
+	// Format selects the output shape: "text" (default, human-readable),
+	// "json" (a flat array of DiagnosticRecord), or "sarif" (a minimal
+	// SARIF 2.1.0 log, one run per LSP client).
+	Format string `json:"format"`
+}
+
+// DiagnosticRecord is the JSON form of a single LSP diagnostic, preserving
+// fields the text format discards (the end of the range, related
+// locations, tags, and the code's description link).
+type DiagnosticRecord struct {
+	Path               string                      `json:"path"`
+	Line               int                         `json:"line"`
+	Column             int                         `json:"column"`
+	EndLine            int                         `json:"endLine"`
+	EndColumn          int                         `json:"endColumn"`
+	Severity           string                      `json:"severity"`
+	Code               string                      `json:"code,omitempty"`
+	CodeHref           string                      `json:"codeHref,omitempty"`
+	Source             string                      `json:"source"`
+	Message            string                      `json:"message"`
+	RelatedInformation []DiagnosticRelatedLocation `json:"relatedInformation,omitempty"`
+	Tags               []string                    `json:"tags,omitempty"`
+}
+
+// DiagnosticRelatedLocation is the JSON form of a
+// protocol.DiagnosticRelatedInformation entry.
+type DiagnosticRelatedLocation struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+// diagEntry pairs a diagnostic with the file it was reported against, used
+// while a diagnosticsTool.Run call is still grouped by the LSP client that
+// produced each entry.
+type diagEntry struct {
+	path string
+	diag protocol.Diagnostic
 }
 
 type diagnosticsTool struct {
-	root string
-	lsps map[string]*lsp.Client
+	root     string
+	sessions *lsp.SessionManager
 }
 
 func NewDiagnosticsTool(root string) BaseTool {
-	return &diagnosticsTool{root: root, lsps: map[string]*lsp.Client{}}
+	return &diagnosticsTool{root: root, sessions: lsp.NewSessionManager(map[string]*lsp.Client{})}
 }
 
 func (d *diagnosticsTool) Info() ToolInfo {
@@ -35,6 +89,10 @@ func (d *diagnosticsTool) Info() ToolInfo {
 				"type":        "string",
 				"description": "The path to the file to get diagnostics for (leave empty for project diagnostics)",
 			},
+			"format": map[string]any{
+				"type":        "string",
+				"description": "Output format: \"text\" (default), \"json\", or \"sarif\"",
+			},
 		},
 		Required: []string{},
 	}
@@ -45,34 +103,84 @@ func (d *diagnosticsTool) Run(ctx context.Context, call ToolCall) (ToolResponse,
 	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
 		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
 	}
-	if len(d.lsps) == 0 {
+	if len(d.sessions.Clients()) == 0 {
 		return NewTextErrorResponse("no LSP clients available"), nil
 	}
 
+	format := strings.ToLower(strings.TrimSpace(params.Format))
+	if format == "" {
+		format = "text"
+	}
+	if format != "text" && format != "json" && format != "sarif" {
+		return NewTextErrorResponse(fmt.Sprintf("unsupported format %q: expected text, json, or sarif", params.Format)), nil
+	}
+
 	filePath := strings.TrimSpace(params.FilePath)
+	var fileAbs string
 	if filePath != "" {
-		fileAbs, err := absClean(filePath)
+		var err error
+		fileAbs, err = absClean(filePath)
 		if err != nil {
 			return NewTextErrorResponse(err.Error()), nil
 		}
 		if d.root != "" && !isWithinRoot(d.root, fileAbs) {
 			return NewTextErrorResponse("path is outside workspace root"), nil
 		}
-		return NewTextResponse(diagnosticsForFile(ctx, fileAbs, d.lsps)), nil
 	}
 
-	return NewTextResponse(projectDiagnostics(d.root, d.lsps)), nil
+	if format == "text" {
+		if fileAbs != "" {
+			return NewTextResponse(diagnosticsForFile(ctx, fileAbs, d.sessions.Clients())), nil
+		}
+		return NewTextResponse(projectDiagnostics(d.root, d.sessions.Clients())), nil
+	}
+
+	byClient := d.collectDiagnostics(ctx, fileAbs)
+
+	var out []byte
+	var err error
+	if format == "json" {
+		out, err = json.MarshalIndent(diagnosticRecords(byClient), "", "  ")
+	} else {
+		out, err = json.MarshalIndent(sarifLogFor(byClient), "", "  ")
+	}
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error encoding diagnostics: %w", err)
+	}
+	return NewTextResponse(string(out)), nil
+}
+
+// collectDiagnostics gathers every diagnostic for filePath (or, when
+// filePath is empty, the whole project) grouped by the LSP client name
+// that reported it.
+func (d *diagnosticsTool) collectDiagnostics(ctx context.Context, filePath string) map[string][]diagEntry {
+	byClient := map[string][]diagEntry{}
+	if filePath != "" {
+		for name, ds := range waitForFileDiagnostics(ctx, filePath, d.sessions.Clients()) {
+			for _, dg := range ds {
+				byClient[name] = append(byClient[name], diagEntry{path: filePath, diag: dg})
+			}
+		}
+		return byClient
+	}
+
+	for name, client := range d.sessions.Clients() {
+		for uri, diags := range client.GetDiagnostics() {
+			pth := uri.Path()
+			if d.root != "" && !isWithinRoot(d.root, pth) {
+				continue
+			}
+			for _, dg := range diags {
+				byClient[name] = append(byClient[name], diagEntry{path: pth, diag: dg})
+			}
+		}
+	}
+	return byClient
 }
 
 func diagnosticsForFile(ctx context.Context, filePath string, lsps map[string]*lsp.Client) string {
 	lines := make([]string, 0)
-	for name, client := range lsps {
-		_ = client.OpenFile(ctx, filePath)
-		_ = client.NotifyChange(ctx, filePath)
-		ds, err := client.GetDiagnosticsForFile(ctx, filePath)
-		if err != nil {
-			continue
-		}
+	for name, ds := range waitForFileDiagnostics(ctx, filePath, lsps) {
 		for _, d := range ds {
 			lines = append(lines, formatDiagnostic(filePath, d, name))
 		}
@@ -84,6 +192,45 @@ func diagnosticsForFile(ctx context.Context, filePath string, lsps map[string]*l
 	return fmt.Sprintf("Diagnostics for %s:\n%s", filePath, strings.Join(lines, "\n"))
 }
 
+// waitForFileDiagnostics notifies every attached LSP client about filePath
+// and waits for each to settle on a fresh set of diagnostics, querying all
+// clients concurrently so one slow server doesn't hold up the rest.
+//
+// "Settle" means WaitForDiagnostics has seen either a quiet period after a
+// publishDiagnostics notification for the file or its own timeout elapse;
+// without this, GetDiagnosticsForFile called right after NotifyChange races
+// the server's analysis and routinely returns stale or empty results.
+func waitForFileDiagnostics(ctx context.Context, filePath string, lsps map[string]*lsp.Client) map[string][]protocol.Diagnostic {
+	uri := protocol.DocumentURI("file://" + filePath)
+	content, _ := os.ReadFile(filePath)
+
+	results := map[string][]protocol.Diagnostic{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, client := range lsps {
+		wg.Add(1)
+		go func(name string, client *lsp.Client) {
+			defer wg.Done()
+			sess, err := client.Acquire(ctx, filePath)
+			if err != nil {
+				return
+			}
+			defer sess.Release()
+			_ = sess.Sync(ctx, content)
+			client.WaitForDiagnostics(ctx, uri, diagnosticsQuietPeriod, diagnosticsMaxWait)
+			ds, err := client.GetDiagnosticsForFile(ctx, filePath)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			results[name] = ds
+			mu.Unlock()
+		}(name, client)
+	}
+	wg.Wait()
+	return results
+}
+
 func projectDiagnostics(root string, lsps map[string]*lsp.Client) string {
 	lines := make([]string, 0)
 	for name, client := range lsps {
@@ -105,25 +252,217 @@ func projectDiagnostics(root string, lsps map[string]*lsp.Client) string {
 }
 
 func formatDiagnostic(path string, d protocol.Diagnostic, source string) string {
-	severity := "Info"
-	switch d.Severity {
+	src := d.Source
+	if src == "" {
+		src = source
+	}
+	code := ""
+	if d.Code != nil {
+		code = fmt.Sprintf("[%v]", d.Code)
+	}
+	loc := fmt.Sprintf("%s:%d:%d", path, d.Range.Start.Line+1, d.Range.Start.Character+1)
+	return fmt.Sprintf("%s: %s [%s]%s %s", severityName(d.Severity), loc, src, code, d.Message)
+}
+
+// severityName renders an LSP diagnostic severity the way the text format
+// always has.
+func severityName(sev protocol.DiagnosticSeverity) string {
+	switch sev {
 	case protocol.SeverityError:
-		severity = "Error"
+		return "Error"
 	case protocol.SeverityWarning:
-		severity = "Warn"
+		return "Warn"
 	case protocol.SeverityHint:
-		severity = "Hint"
+		return "Hint"
 	case protocol.SeverityInformation:
-		severity = "Info"
+		return "Info"
+	default:
+		return "Info"
+	}
+}
+
+// diagnosticTagName renders an LSP diagnostic tag constant as a string for
+// the JSON/SARIF output.
+func diagnosticTagName(tag protocol.DiagnosticTag) string {
+	switch tag {
+	case protocol.Unnecessary:
+		return "Unnecessary"
+	case protocol.Deprecated:
+		return "Deprecated"
+	default:
+		return fmt.Sprintf("Tag(%d)", tag)
 	}
+}
+
+// diagnosticRecords flattens every client's diagnostics into one array,
+// sorted by path then line for stable output.
+func diagnosticRecords(byClient map[string][]diagEntry) []DiagnosticRecord {
+	var records []DiagnosticRecord
+	for client, entries := range byClient {
+		for _, e := range entries {
+			records = append(records, toDiagnosticRecord(e.path, e.diag, client))
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Path != records[j].Path {
+			return records[i].Path < records[j].Path
+		}
+		return records[i].Line < records[j].Line
+	})
+	return records
+}
+
+func toDiagnosticRecord(path string, d protocol.Diagnostic, clientName string) DiagnosticRecord {
 	src := d.Source
 	if src == "" {
-		src = source
+		src = clientName
+	}
+	rec := DiagnosticRecord{
+		Path:      path,
+		Line:      d.Range.Start.Line + 1,
+		Column:    d.Range.Start.Character + 1,
+		EndLine:   d.Range.End.Line + 1,
+		EndColumn: d.Range.End.Character + 1,
+		Severity:  severityName(d.Severity),
+		Source:    src,
+		Message:   d.Message,
 	}
-	code := ""
 	if d.Code != nil {
-		code = fmt.Sprintf("[%v]", d.Code)
+		rec.Code = fmt.Sprintf("%v", d.Code)
+	}
+	if d.CodeDescription != nil {
+		rec.CodeHref = d.CodeDescription.Href
+	}
+	for _, ri := range d.RelatedInformation {
+		rec.RelatedInformation = append(rec.RelatedInformation, DiagnosticRelatedLocation{
+			Path:    ri.Location.URI.Path(),
+			Line:    ri.Location.Range.Start.Line + 1,
+			Column:  ri.Location.Range.Start.Character + 1,
+			Message: ri.Message,
+		})
+	}
+	for _, tag := range d.Tags {
+		rec.Tags = append(rec.Tags, diagnosticTagName(tag))
+	}
+	return rec
+}
+
+// sarifLog is a minimal SARIF 2.1.0 log: a $schema/version envelope around
+// one run per LSP client.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name string `json:"name"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+// sarifLogFor builds one SARIF run per LSP client, each named after the
+// client and sorted by path then line so the output is stable.
+func sarifLogFor(byClient map[string][]diagEntry) sarifLog {
+	names := make([]string, 0, len(byClient))
+	for name := range byClient {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	runs := make([]sarifRun, 0, len(names))
+	for _, name := range names {
+		entries := append([]diagEntry(nil), byClient[name]...)
+		sort.Slice(entries, func(i, j int) bool {
+			if entries[i].path != entries[j].path {
+				return entries[i].path < entries[j].path
+			}
+			return entries[i].diag.Range.Start.Line < entries[j].diag.Range.Start.Line
+		})
+
+		results := make([]sarifResult, 0, len(entries))
+		for _, e := range entries {
+			results = append(results, toSARIFResult(e.path, e.diag))
+		}
+		runs = append(runs, sarifRun{Tool: sarifTool{Driver: sarifDriver{Name: name}}, Results: results})
+	}
+
+	return sarifLog{Schema: sarifSchemaURI, Version: "2.1.0", Runs: runs}
+}
+
+func toSARIFResult(path string, d protocol.Diagnostic) sarifResult {
+	ruleID := "unknown"
+	if d.Code != nil {
+		ruleID = fmt.Sprintf("%v", d.Code)
+	}
+	return sarifResult{
+		RuleID:  ruleID,
+		Level:   sarifLevel(d.Severity),
+		Message: sarifMessage{Text: d.Message},
+		Locations: []sarifLocation{{
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: "file://" + path},
+				Region: sarifRegion{
+					StartLine:   d.Range.Start.Line + 1,
+					StartColumn: d.Range.Start.Character + 1,
+					EndLine:     d.Range.End.Line + 1,
+					EndColumn:   d.Range.End.Character + 1,
+				},
+			},
+		}},
+	}
+}
+
+// sarifLevel maps an LSP severity to the closest SARIF result level.
+func sarifLevel(sev protocol.DiagnosticSeverity) string {
+	switch sev {
+	case protocol.SeverityError:
+		return "error"
+	case protocol.SeverityWarning:
+		return "warning"
+	case protocol.SeverityInformation:
+		return "note"
+	case protocol.SeverityHint:
+		return "none"
+	default:
+		return "warning"
 	}
-	loc := fmt.Sprintf("%s:%d:%d", path, d.Range.Start.Line+1, d.Range.Start.Character+1)
-	return fmt.Sprintf("%s: %s [%s]%s %s", severity, loc, src, code, d.Message)
 }
@@ -0,0 +1,33 @@
+package tools
+
+import "os"
+
+// statPath, openPath, readFilePath, and readDirPath are what every
+// file-opening tool (view, write, glob, grep) should call instead of the
+// os package directly, so the Windows extended-length path prefix
+// (toExtendedPath) is applied consistently everywhere a path reaches the
+// filesystem. On other platforms toExtendedPath is a no-op and these are
+// thin passthroughs.
+func statPath(path string) (os.FileInfo, error) {
+	return os.Stat(toExtendedPath(path))
+}
+
+func openPath(path string) (*os.File, error) {
+	return os.Open(toExtendedPath(path))
+}
+
+func readFilePath(path string) ([]byte, error) {
+	return os.ReadFile(toExtendedPath(path))
+}
+
+func readDirPath(path string) ([]os.DirEntry, error) {
+	return os.ReadDir(toExtendedPath(path))
+}
+
+func writeFilePath(path string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(toExtendedPath(path), data, perm)
+}
+
+func mkdirAllPath(path string, perm os.FileMode) error {
+	return os.MkdirAll(toExtendedPath(path), perm)
+}
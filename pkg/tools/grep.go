@@ -2,10 +2,10 @@ package tools
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -15,7 +15,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/example/demo-tools-bridge/pkg/config"
 	"github.com/example/demo-tools-bridge/pkg/fileutil"
+	"github.com/example/demo-tools-bridge/pkg/ignore"
+	"github.com/example/demo-tools-bridge/pkg/selector"
 )
 
 const (
@@ -32,6 +35,7 @@ HOW TO USE:
 - Set literal_text=true if you want to search for the exact text with special characters (recommended for non-regex users)
 - Optionally specify a starting directory (defaults to current working directory)
 - Optionally provide an include pattern to filter which files to search
+- Optionally set 'max_file_size', 'modified_since', and/or 'exclude' to narrow which files are scanned
 - Results are sorted with most recently modified files first
 
 REGEX PATTERN SYNTAX (when literal_text=false):
@@ -45,6 +49,15 @@ COMMON INCLUDE PATTERN EXAMPLES:
 - '*.{ts,tsx}' - Only search TypeScript files
 - '*.go' - Only search Go files
 
+CONTEXT AND MULTILINE:
+- Set 'context' to N to show N lines of surrounding context before and after each match (or use 'before'/'after' independently)
+- Set 'multiline=true' to let the pattern match across line boundaries (e.g. a struct definition spanning several lines)
+- Set 'count_only=true' to get per-file match counts instead of line previews
+
+MULTI-PATTERN SEARCH:
+- Provide 'patterns' (a list) instead of, or alongside, 'pattern' to search for several things in one pass
+- Set 'fixed_strings=true' together with 'literal_text=true' to search dozens of literal strings (e.g. a list of identifiers) efficiently instead of compiling a large alternation regex
+
 LIMITATIONS:
 - Results are limited to 100 files (newest first)
 - Performance depends on the number of files being searched
@@ -60,18 +73,55 @@ TIPS:
 
 const maxFallbackFileSize = 1 * 1024 * 1024
 
-var rgOutputLineRE = regexp.MustCompile(`^(.*?):(\d+):(.*)$`)
+var (
+	rgOutputLineRE  = regexp.MustCompile(`^(.*?):(\d+):(.*)$`)
+	rgContextLineRE = regexp.MustCompile(`^(.*?)-(\d+)-(.*)$`)
+	rgCountLineRE   = regexp.MustCompile(`^(.*?):(\d+)$`)
+)
 
 type GrepParams struct {
 	Pattern     string `json:"pattern"`
 	Path        string `json:"path"`
 	Include     string `json:"include"`
 	LiteralText bool   `json:"literal_text"`
+	Context     int    `json:"context"`
+	Before      int    `json:"before"`
+	After       int    `json:"after"`
+	Multiline   bool   `json:"multiline"`
+	CountOnly   bool   `json:"count_only"`
+	// MaxFileSize caps how large a file the regex fallback will read, in
+	// bytes. 0 uses maxFallbackFileSize or the workspace's configured
+	// Grep.MaxFileSize default.
+	MaxFileSize int64 `json:"max_file_size"`
+	// ModifiedSince, if set, skips files last modified before this time
+	// (RFC 3339, e.g. "2024-01-02T15:04:05Z").
+	ModifiedSince string `json:"modified_since"`
+	// Exclude is a glob pattern (matched against the base name) for files
+	// to skip in addition to the workspace's configured Grep.Exclude list.
+	Exclude string `json:"exclude"`
+	// Patterns searches for several patterns in one pass, in addition to
+	// Pattern if it is also set.
+	Patterns []string `json:"patterns"`
+	// FixedStrings, combined with LiteralText, matches Pattern/Patterns as
+	// literal strings via a single Aho-Corasick automaton instead of a
+	// regex alternation, which stays fast as the pattern count grows.
+	FixedStrings bool `json:"fixed_strings"`
 }
 
 type GrepResponseMetadata struct {
-	NumberOfMatches int  `json:"number_of_matches"`
-	Truncated       bool `json:"truncated"`
+	NumberOfMatches int            `json:"number_of_matches"`
+	Truncated       bool           `json:"truncated"`
+	FileCounts      map[string]int `json:"file_counts,omitempty"`
+}
+
+// grepOptions controls how a search is executed: how much surrounding
+// context to capture around each match, whether the pattern is allowed to
+// span line boundaries, and whether callers just want per-file counts.
+type grepOptions struct {
+	Before    int
+	After     int
+	Multiline bool
+	CountOnly bool
 }
 
 type grepMatch struct {
@@ -79,6 +129,20 @@ type grepMatch struct {
 	modTime  time.Time
 	lineNum  int
 	lineText string
+	before   []string
+	after    []string
+	// patterns lists which of a multi-pattern fixed_strings search's
+	// patterns matched this line. Left nil for ordinary single-regex
+	// searches.
+	patterns []string
+}
+
+// grepQuery bundles the pattern set a search runs against and whether it
+// should be matched as literal strings via Aho-Corasick (fixedStrings) or
+// as a regex (joined into one alternation when there's more than one).
+type grepQuery struct {
+	patterns     []string
+	fixedStrings bool
 }
 
 type grepTool struct {
@@ -110,8 +174,48 @@ func (g *grepTool) Info() ToolInfo {
 				"type":        "boolean",
 				"description": "If true, the pattern will be treated as literal text with special regex characters escaped. Default is false.",
 			},
+			"context": map[string]any{
+				"type":        "integer",
+				"description": "Number of lines of context to show before and after each match. Overridden by before/after if set.",
+			},
+			"before": map[string]any{
+				"type":        "integer",
+				"description": "Number of lines of context to show before each match",
+			},
+			"after": map[string]any{
+				"type":        "integer",
+				"description": "Number of lines of context to show after each match",
+			},
+			"multiline": map[string]any{
+				"type":        "boolean",
+				"description": "If true, the pattern may match across line boundaries",
+			},
+			"count_only": map[string]any{
+				"type":        "boolean",
+				"description": "If true, return per-file match counts instead of line previews",
+			},
+			"max_file_size": map[string]any{
+				"type":        "integer",
+				"description": "Skip files larger than this many bytes",
+			},
+			"modified_since": map[string]any{
+				"type":        "string",
+				"description": "Skip files last modified before this RFC 3339 timestamp, e.g. \"2024-01-02T15:04:05Z\"",
+			},
+			"exclude": map[string]any{
+				"type":        "string",
+				"description": "Glob pattern (matched against the base name) for files to skip, e.g. \"*.min.js\"",
+			},
+			"patterns": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Search for several patterns in one pass, in addition to pattern if it is also set",
+			},
+			"fixed_strings": map[string]any{
+				"type":        "boolean",
+				"description": "With literal_text=true, match pattern/patterns as literal strings via Aho-Corasick instead of a regex alternation",
+			},
 		},
-		Required: []string{"pattern"},
 	}
 }
 
@@ -120,14 +224,22 @@ func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
 		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
 	}
-	if params.Pattern == "" {
-		return NewTextErrorResponse("pattern is required"), nil
+	var patterns []string
+	patterns = append(patterns, params.Patterns...)
+	if params.Pattern != "" {
+		patterns = append(patterns, params.Pattern)
+	}
+	if len(patterns) == 0 {
+		return NewTextErrorResponse("pattern or patterns is required"), nil
 	}
 
-	searchPattern := params.Pattern
-	if params.LiteralText {
-		searchPattern = escapeRegexPattern(params.Pattern)
+	fixedStrings := params.FixedStrings && params.LiteralText
+	if params.LiteralText && !fixedStrings {
+		for i, p := range patterns {
+			patterns[i] = escapeRegexPattern(p)
+		}
 	}
+	query := grepQuery{patterns: patterns, fixedStrings: fixedStrings}
 
 	searchPath := params.Path
 	if searchPath == "" {
@@ -141,7 +253,46 @@ func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		return NewTextErrorResponse("path is outside workspace root"), nil
 	}
 
-	matches, truncated, err := searchFiles(searchPattern, searchAbs, params.Include, 100)
+	opts := grepOptions{
+		Before:    params.Before,
+		After:     params.After,
+		Multiline: params.Multiline,
+		CountOnly: params.CountOnly,
+	}
+	if params.Context > 0 {
+		if opts.Before == 0 {
+			opts.Before = params.Context
+		}
+		if opts.After == 0 {
+			opts.After = params.Context
+		}
+	}
+
+	var ig *ignore.Ignore
+	if g.root != "" {
+		ig, err = ignore.ForDir(g.root)
+		if err != nil {
+			return ToolResponse{}, fmt.Errorf("error loading ignore rules: %w", err)
+		}
+	}
+
+	filters, err := buildGrepFilters(params)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	if opts.CountOnly {
+		counts, err := countFiles(query, searchAbs, params.Include, filters, opts, ig)
+		if err != nil {
+			return ToolResponse{}, fmt.Errorf("error searching files: %w", err)
+		}
+		return WithResponseMetadata(
+			NewTextResponse(formatCounts(counts)),
+			GrepResponseMetadata{NumberOfMatches: totalCount(counts), FileCounts: counts},
+		), nil
+	}
+
+	matches, truncated, err := searchFiles(query, searchAbs, params.Include, 100, filters, opts, ig)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("error searching files: %w", err)
 	}
@@ -158,11 +309,7 @@ func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 				currentFile = match.path
 				out += fmt.Sprintf("%s:\n", match.path)
 			}
-			if match.lineNum > 0 {
-				out += fmt.Sprintf("  Line %d: %s\n", match.lineNum, match.lineText)
-			} else {
-				out += fmt.Sprintf("  %s\n", match.path)
-			}
+			out += formatHunk(match)
 		}
 		if truncated {
 			out += "\n(Results are truncated. Consider using a more specific path or pattern.)"
@@ -175,6 +322,53 @@ func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	), nil
 }
 
+// formatHunk renders a single match together with its surrounding context,
+// similar to `rg -C`: context lines use "-" as the line-number separator,
+// the match line uses ":".
+func formatHunk(match grepMatch) string {
+	var b strings.Builder
+	line := match.lineNum - len(match.before)
+	for _, text := range match.before {
+		b.WriteString(fmt.Sprintf("  %d- %s\n", line, text))
+		line++
+	}
+	b.WriteString(fmt.Sprintf("  %d: %s\n", match.lineNum, match.lineText))
+	if len(match.patterns) > 0 {
+		b.WriteString(fmt.Sprintf("    matched: %s\n", strings.Join(match.patterns, ", ")))
+	}
+	line = match.lineNum + 1
+	for _, text := range match.after {
+		b.WriteString(fmt.Sprintf("  %d- %s\n", line, text))
+		line++
+	}
+	return b.String()
+}
+
+func formatCounts(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "No files found"
+	}
+	paths := make([]string, 0, len(counts))
+	for p := range counts {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	var b strings.Builder
+	fmt.Fprintf(&b, "Found matches in %d files\n", len(paths))
+	for _, p := range paths {
+		fmt.Fprintf(&b, "  %s: %d\n", p, counts[p])
+	}
+	return b.String()
+}
+
+func totalCount(counts map[string]int) int {
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	return total
+}
+
 func escapeRegexPattern(pattern string) string {
 	specialChars := []string{"\\", ".", "+", "*", "?", "(", ")", "[", "]", "{", "}", "^", "$", "|"}
 	escaped := pattern
@@ -184,10 +378,97 @@ func escapeRegexPattern(pattern string) string {
 	return escaped
 }
 
-func searchFiles(pattern, rootPath, include string, limit int) ([]grepMatch, bool, error) {
-	matches, err := searchWithRipgrep(pattern, rootPath, include)
+// joinPatterns combines several regex patterns into one alternation so the
+// regex fallback can still do a single pass even when fixed_strings isn't
+// set. A single pattern is returned unchanged.
+func joinPatterns(patterns []string) string {
+	if len(patterns) == 1 {
+		return patterns[0]
+	}
+	wrapped := make([]string, len(patterns))
+	for i, p := range patterns {
+		wrapped[i] = "(?:" + p + ")"
+	}
+	return strings.Join(wrapped, "|")
+}
+
+// grepFilters bundles the file-selection criteria that both the ripgrep
+// path and the regex-fallback path need to honor identically: a size cap,
+// extra exclude globs, and a modified-since cutoff. Building this once in
+// Run and threading it through both paths is what keeps ripgrepArgs (and
+// its count-mode counterpart) in sync with buildSelector instead of only
+// the regex fallback actually respecting max_file_size/exclude.
+type grepFilters struct {
+	maxFileSize   int64
+	excludes      []string
+	modifiedSince time.Time
+}
+
+func buildGrepFilters(params GrepParams) (grepFilters, error) {
+	cfg := config.Get()
+
+	maxSize := int64(maxFallbackFileSize)
+	if cfg.Grep.MaxFileSize > 0 {
+		maxSize = cfg.Grep.MaxFileSize
+	}
+	if params.MaxFileSize > 0 {
+		maxSize = params.MaxFileSize
+	}
+
+	excludes := append([]string{}, cfg.Grep.Exclude...)
+	if params.Exclude != "" {
+		excludes = append(excludes, params.Exclude)
+	}
+
+	var since time.Time
+	if params.ModifiedSince != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, params.ModifiedSince)
+		if err != nil {
+			return grepFilters{}, fmt.Errorf("invalid modified_since: %w", err)
+		}
+	}
+
+	return grepFilters{maxFileSize: maxSize, excludes: excludes, modifiedSince: since}, nil
+}
+
+// buildSelector assembles the selector.SelectFunc the regex fallback walk
+// uses to decide which files to read. Hidden files and likely-binary files
+// are always excluded in addition to filters.
+func buildSelector(filters grepFilters) selector.SelectFunc {
+	fns := []selector.SelectFunc{selector.NotHidden(), selector.BySize(filters.maxFileSize), selector.NotBinary()}
+
+	for _, pattern := range filters.excludes {
+		fns = append(fns, selector.Not(selector.ByGlob(pattern)))
+	}
+	if !filters.modifiedSince.IsZero() {
+		fns = append(fns, selector.ByModTimeAfter(filters.modifiedSince))
+	}
+
+	return selector.All(fns...)
+}
+
+// rgFileFilterArgs translates filters' size cap and exclude globs into
+// ripgrep flags, so the ripgrep path matches buildSelector's equivalent
+// behavior for the regex fallback instead of silently ignoring them.
+// modifiedSince has no ripgrep flag equivalent; callers post-filter
+// ripgrep's results against it by stat'ing each match instead.
+func rgFileFilterArgs(filters grepFilters) []string {
+	var args []string
+	if filters.maxFileSize > 0 {
+		args = append(args, "--max-filesize", strconv.FormatInt(filters.maxFileSize, 10))
+	}
+	for _, pattern := range filters.excludes {
+		args = append(args, "--glob", "!"+pattern)
+	}
+	return args
+}
+
+func searchFiles(q grepQuery, rootPath, include string, limit int, filters grepFilters, opts grepOptions, ig *ignore.Ignore) ([]grepMatch, bool, error) {
+	matches, err := searchWithRipgrep(q, rootPath, include, opts, ig, filters)
 	if err != nil {
-		matches, err = searchFilesWithRegex(pattern, rootPath, include)
+		sel := buildSelector(filters)
+		matches, err = searchFilesWithRegex(q, rootPath, include, sel, opts, ig)
 		if err != nil {
 			return nil, false, err
 		}
@@ -204,16 +485,70 @@ func searchFiles(pattern, rootPath, include string, limit int) ([]grepMatch, boo
 	return matches, truncated, nil
 }
 
-func searchWithRipgrep(pattern, path, include string) ([]grepMatch, error) {
-	_, err := exec.LookPath("rg")
+func countFiles(q grepQuery, rootPath, include string, filters grepFilters, opts grepOptions, ig *ignore.Ignore) (map[string]int, error) {
+	counts, err := countWithRipgrep(q, rootPath, include, opts, ig, filters)
 	if err != nil {
-		return nil, fmt.Errorf("ripgrep not found: %w", err)
+		sel := buildSelector(filters)
+		counts, err = countFilesWithRegex(q, rootPath, include, sel, opts, ig)
+		if err != nil {
+			return nil, err
+		}
 	}
+	return counts, nil
+}
 
-	args := []string{"-H", "-n", pattern}
+func ripgrepArgs(q grepQuery, include string, opts grepOptions, ig *ignore.Ignore, filters grepFilters) []string {
+	args := []string{}
+	if opts.Multiline {
+		args = append(args, "--multiline", "--multiline-dotall")
+	}
+	if opts.Before == opts.After && opts.Before > 0 {
+		args = append(args, "-C", strconv.Itoa(opts.Before))
+	} else {
+		if opts.Before > 0 {
+			args = append(args, "-B", strconv.Itoa(opts.Before))
+		}
+		if opts.After > 0 {
+			args = append(args, "-A", strconv.Itoa(opts.After))
+		}
+	}
+	if q.fixedStrings {
+		args = append(args, "-F")
+	}
+	args = append(args, "-H", "-n")
+	for _, p := range q.patterns {
+		args = append(args, "-e", p)
+	}
 	if include != "" {
 		args = append(args, "--glob", include)
 	}
+	if path := lspIgnoreFile(ig); path != "" {
+		args = append(args, "--ignore-file", path)
+	}
+	args = append(args, rgFileFilterArgs(filters)...)
+	return args
+}
+
+// lspIgnoreFile returns the path to the workspace's .lspignore file, if
+// any, so ripgrep (which already honors .gitignore/.ignore on its own) can
+// be told about the one ignore file it doesn't know about natively.
+func lspIgnoreFile(ig *ignore.Ignore) string {
+	if ig == nil || !ig.HasPatterns() {
+		return ""
+	}
+	path := filepath.Join(ig.Root(), ".lspignore")
+	if _, err := statPath(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+func searchWithRipgrep(q grepQuery, path, include string, opts grepOptions, ig *ignore.Ignore, filters grepFilters) ([]grepMatch, error) {
+	if _, err := exec.LookPath("rg"); err != nil {
+		return nil, fmt.Errorf("ripgrep not found: %w", err)
+	}
+
+	args := ripgrepArgs(q, include, opts, ig, filters)
 	args = append(args, path)
 
 	cmd := exec.Command("rg", args...)
@@ -225,59 +560,150 @@ func searchWithRipgrep(pattern, path, include string) ([]grepMatch, error) {
 		return nil, err
 	}
 
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	matches := make([]grepMatch, 0, len(lines))
+	matches := parseRipgrepOutput(string(output))
+	result := matches[:0]
+	for _, match := range matches {
+		fileInfo, err := statPath(match.path)
+		if err != nil {
+			continue
+		}
+		if fileutil.SkipHidden(match.path) {
+			continue
+		}
+		// ripgrep has no mtime flag, so modified_since is applied here
+		// instead of translated into an rg argument.
+		if !filters.modifiedSince.IsZero() && fileInfo.ModTime().Before(filters.modifiedSince) {
+			continue
+		}
+		match.modTime = fileInfo.ModTime()
+		result = append(result, match)
+	}
+
+	return result, nil
+}
+
+// parseRipgrepOutput groups ripgrep's `-C`/`-A`/`-B` output into hunks. Match
+// lines are separated from the path and line number with ":"; context lines
+// use "-" instead. Hunks are separated by a bare "--" line.
+func parseRipgrepOutput(output string) []grepMatch {
+	var matches []grepMatch
+	var current *grepMatch
+	var pendingBefore []string
 
-	for _, line := range lines {
+	for _, line := range strings.Split(output, "\n") {
 		if line == "" {
 			continue
 		}
-		m := rgOutputLineRE.FindStringSubmatch(line)
-		if len(m) != 4 {
+		if line == "--" {
+			current = nil
+			pendingBefore = nil
 			continue
 		}
-
-		filePath := m[1]
-		lineNum, err := strconv.Atoi(m[2])
-		if err != nil {
+		if m := rgOutputLineRE.FindStringSubmatch(line); m != nil {
+			lineNum, err := strconv.Atoi(m[2])
+			if err != nil {
+				continue
+			}
+			matches = append(matches, grepMatch{
+				path:     m[1],
+				lineNum:  lineNum,
+				lineText: m[3],
+				before:   pendingBefore,
+			})
+			current = &matches[len(matches)-1]
+			pendingBefore = nil
 			continue
 		}
-		lineText := m[3]
+		if m := rgContextLineRE.FindStringSubmatch(line); m != nil {
+			if current != nil {
+				current.after = append(current.after, m[3])
+			} else {
+				pendingBefore = append(pendingBefore, m[3])
+			}
+		}
+	}
+
+	return matches
+}
+
+func countWithRipgrep(q grepQuery, path, include string, opts grepOptions, ig *ignore.Ignore, filters grepFilters) (map[string]int, error) {
+	if _, err := exec.LookPath("rg"); err != nil {
+		return nil, fmt.Errorf("ripgrep not found: %w", err)
+	}
+
+	args := []string{"-c"}
+	if opts.Multiline {
+		args = append(args, "--multiline", "--multiline-dotall")
+	}
+	if q.fixedStrings {
+		args = append(args, "-F")
+	}
+	for _, p := range q.patterns {
+		args = append(args, "-e", p)
+	}
+	if include != "" {
+		args = append(args, "--glob", include)
+	}
+	if p := lspIgnoreFile(ig); p != "" {
+		args = append(args, "--ignore-file", p)
+	}
+	args = append(args, rgFileFilterArgs(filters)...)
+	args = append(args, path)
 
-		fileInfo, err := os.Stat(filePath)
+	cmd := exec.Command("rg", args...)
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return map[string]int{}, nil
+		}
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line == "" {
+			continue
+		}
+		m := rgCountLineRE.FindStringSubmatch(line)
+		if len(m) != 3 {
+			continue
+		}
+		n, err := strconv.Atoi(m[2])
 		if err != nil {
 			continue
 		}
-
-		if fileutil.SkipHidden(filePath) {
+		if fileutil.SkipHidden(m[1]) {
 			continue
 		}
-
-		matches = append(matches, grepMatch{
-			path:     filePath,
-			modTime:  fileInfo.ModTime(),
-			lineNum:  lineNum,
-			lineText: lineText,
-		})
+		// ripgrep has no mtime flag, so modified_since is applied here
+		// instead of translated into an rg argument.
+		if !filters.modifiedSince.IsZero() {
+			fileInfo, err := statPath(m[1])
+			if err != nil || fileInfo.ModTime().Before(filters.modifiedSince) {
+				continue
+			}
+		}
+		counts[m[1]] = n
 	}
-
-	return matches, nil
+	return counts, nil
 }
 
-func searchFilesWithRegex(pattern, rootPath, include string) ([]grepMatch, error) {
+func searchFilesWithRegex(q grepQuery, rootPath, include string, sel selector.SelectFunc, opts grepOptions, ig *ignore.Ignore) ([]grepMatch, error) {
 	matches := []grepMatch{}
 
-	regex, err := regexp.Compile(pattern)
+	includePattern, err := compileIncludePattern(include)
 	if err != nil {
-		return nil, fmt.Errorf("invalid regex pattern: %w", err)
+		return nil, err
 	}
 
-	var includePattern *regexp.Regexp
-	if include != "" {
-		regexPattern := globToRegex(include)
-		includePattern, err = regexp.Compile(regexPattern)
+	var regex *regexp.Regexp
+	var ac *ahoCorasick
+	if q.fixedStrings {
+		ac = newAhoCorasick(q.patterns)
+	} else {
+		regex, err = compileGrepRegex(joinPatterns(q.patterns), opts)
 		if err != nil {
-			return nil, fmt.Errorf("invalid include pattern: %w", err)
+			return nil, err
 		}
 	}
 
@@ -286,36 +712,32 @@ func searchFilesWithRegex(pattern, rootPath, include string) ([]grepMatch, error
 			return nil
 		}
 		if info.IsDir() {
-			if fileutil.SkipHidden(path) {
+			if isIgnoredPath(ig, path) || !sel(path, info) {
 				return filepath.SkipDir
 			}
 			return nil
 		}
-		if fileutil.SkipHidden(path) {
-			return nil
-		}
-		if info.Size() > maxFallbackFileSize {
+		if isIgnoredPath(ig, path) || !sel(path, info) {
 			return nil
 		}
 		if includePattern != nil && !includePattern.MatchString(path) {
 			return nil
 		}
-		isBinary, binErr := isLikelyBinaryFile(path)
-		if binErr != nil || isBinary {
-			return nil
-		}
 
-		match, lineNum, lineText, err := fileContainsPattern(path, regex)
+		var match grepMatch
+		var found bool
+		if ac != nil {
+			match, found, err = fileContainsPatternAC(path, ac, q.patterns, opts)
+		} else {
+			match, found, err = fileContainsPattern(path, regex, opts)
+		}
 		if err != nil {
 			return nil
 		}
-		if match {
-			matches = append(matches, grepMatch{
-				path:     path,
-				modTime:  info.ModTime(),
-				lineNum:  lineNum,
-				lineText: lineText,
-			})
+		if found {
+			match.path = path
+			match.modTime = info.ModTime()
+			matches = append(matches, match)
 			if len(matches) >= 200 {
 				return filepath.SkipAll
 			}
@@ -328,46 +750,367 @@ func searchFilesWithRegex(pattern, rootPath, include string) ([]grepMatch, error
 	return matches, nil
 }
 
-func fileContainsPattern(filePath string, pattern *regexp.Regexp) (bool, int, string, error) {
-	file, err := os.Open(filePath)
+func countFilesWithRegex(q grepQuery, rootPath, include string, sel selector.SelectFunc, opts grepOptions, ig *ignore.Ignore) (map[string]int, error) {
+	includePattern, err := compileIncludePattern(include)
 	if err != nil {
-		return false, 0, "", err
+		return nil, err
+	}
+
+	var regex *regexp.Regexp
+	var ac *ahoCorasick
+	if q.fixedStrings {
+		ac = newAhoCorasick(q.patterns)
+	} else {
+		regex, err = compileGrepRegex(joinPatterns(q.patterns), opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	counts := map[string]int{}
+	err = filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if isIgnoredPath(ig, path) || !sel(path, info) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isIgnoredPath(ig, path) || !sel(path, info) {
+			return nil
+		}
+		if includePattern != nil && !includePattern.MatchString(path) {
+			return nil
+		}
+
+		var n int
+		var err error
+		if ac != nil {
+			n, err = countPatternInFileAC(path, ac, opts)
+		} else {
+			n, err = countPatternInFile(path, regex, opts)
+		}
+		if err != nil {
+			return nil
+		}
+		if n > 0 {
+			counts[path] = n
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+func compileGrepRegex(pattern string, opts grepOptions) (*regexp.Regexp, error) {
+	if opts.Multiline {
+		pattern = "(?s)" + pattern
+	}
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex pattern: %w", err)
+	}
+	return regex, nil
+}
+
+// isIgnoredPath reports whether path is matched by the workspace's
+// .gitignore/.ignore/.lspignore rules, if any. Hidden-file filtering is the
+// selector's job (see selector.NotHidden), not this function's.
+func isIgnoredPath(ig *ignore.Ignore, path string) bool {
+	if ig == nil || !ig.HasPatterns() {
+		return false
+	}
+	ignored, _ := ig.Match(path)
+	return ignored
+}
+
+func compileIncludePattern(include string) (*regexp.Regexp, error) {
+	if include == "" {
+		return nil, nil
+	}
+	regexPattern := globToRegex(include)
+	includePattern, err := regexp.Compile(regexPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include pattern: %w", err)
+	}
+	return includePattern, nil
+}
+
+// fileContainsPattern finds the first match of pattern in filePath, along
+// with up to opts.Before/opts.After lines of surrounding context. When
+// opts.Multiline is set the whole file is read into memory so the pattern
+// can scan across line boundaries rather than being matched line-by-line.
+func fileContainsPattern(filePath string, pattern *regexp.Regexp, opts grepOptions) (grepMatch, bool, error) {
+	if opts.Multiline {
+		return fileContainsPatternMultiline(filePath, pattern, opts)
+	}
+
+	file, err := openPath(filePath)
+	if err != nil {
+		return grepMatch{}, false, err
 	}
 	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
 	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var before []string
+	var match *grepMatch
+	afterRemaining := 0
 	lineNum := 0
 	for scanner.Scan() {
 		lineNum++
 		line := scanner.Text()
+
+		if match != nil {
+			if afterRemaining > 0 {
+				match.after = append(match.after, line)
+				afterRemaining--
+				if afterRemaining == 0 {
+					break
+				}
+				continue
+			}
+			break
+		}
+
 		if pattern.MatchString(line) {
-			return true, lineNum, line, nil
+			match = &grepMatch{lineNum: lineNum, lineText: line, before: append([]string(nil), before...)}
+			afterRemaining = opts.After
+			if afterRemaining == 0 {
+				break
+			}
+			continue
+		}
+
+		if opts.Before > 0 {
+			before = append(before, line)
+			if len(before) > opts.Before {
+				before = before[1:]
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return grepMatch{}, false, err
+	}
+	if match == nil {
+		return grepMatch{}, false, nil
+	}
+	return *match, true, nil
+}
+
+// fileContainsPatternAC is the fixed_strings counterpart of
+// fileContainsPattern: it scans with an Aho-Corasick automaton instead of a
+// compiled regexp, and records which of patternTexts matched on the hit
+// line so the caller can report it.
+func fileContainsPatternAC(filePath string, ac *ahoCorasick, patternTexts []string, opts grepOptions) (grepMatch, bool, error) {
+	if opts.Multiline {
+		return fileContainsPatternMultilineAC(filePath, ac, patternTexts, opts)
+	}
+
+	file, err := openPath(filePath)
+	if err != nil {
+		return grepMatch{}, false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var before []string
+	var match *grepMatch
+	afterRemaining := 0
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+
+		if match != nil {
+			if afterRemaining > 0 {
+				match.after = append(match.after, line)
+				afterRemaining--
+				if afterRemaining == 0 {
+					break
+				}
+				continue
+			}
+			break
+		}
+
+		if hits := ac.Match([]byte(line)); len(hits) > 0 {
+			match = &grepMatch{
+				lineNum:  lineNum,
+				lineText: line,
+				before:   append([]string(nil), before...),
+				patterns: patternNames(hits, patternTexts),
+			}
+			afterRemaining = opts.After
+			if afterRemaining == 0 {
+				break
+			}
+			continue
+		}
+
+		if opts.Before > 0 {
+			before = append(before, line)
+			if len(before) > opts.Before {
+				before = before[1:]
+			}
 		}
 	}
+	if err := scanner.Err(); err != nil {
+		return grepMatch{}, false, err
+	}
+	if match == nil {
+		return grepMatch{}, false, nil
+	}
+	return *match, true, nil
+}
+
+func fileContainsPatternMultilineAC(filePath string, ac *ahoCorasick, patternTexts []string, opts grepOptions) (grepMatch, bool, error) {
+	data, err := readFilePath(filePath)
+	if err != nil {
+		return grepMatch{}, false, err
+	}
+
+	end, idx, ok := ac.FirstMatch(data)
+	if !ok {
+		return grepMatch{}, false, nil
+	}
+	start := end - len(patternTexts[idx])
 
-	return false, 0, "", scanner.Err()
+	lineNum := bytes.Count(data[:start], []byte("\n")) + 1
+	lineStart := bytes.LastIndexByte(data[:start], '\n') + 1
+	lineEnd := end
+	if nl := bytes.IndexByte(data[lineEnd:], '\n'); nl >= 0 {
+		lineEnd += nl
+	} else {
+		lineEnd = len(data)
+	}
+	lineText := string(data[lineStart:lineEnd])
+
+	allLines := strings.Split(string(data), "\n")
+	matchIdx := lineNum - 1
+	before := contextSlice(allLines, matchIdx-opts.Before, matchIdx)
+	after := contextSlice(allLines, matchIdx+1, matchIdx+1+opts.After)
+
+	hits := ac.Match(data[start:lineEnd])
+	return grepMatch{
+		lineNum:  lineNum,
+		lineText: lineText,
+		before:   before,
+		after:    after,
+		patterns: patternNames(hits, patternTexts),
+	}, true, nil
 }
 
-func isLikelyBinaryFile(filePath string) (bool, error) {
-	f, err := os.Open(filePath)
+// patternNames maps Aho-Corasick pattern indices back to their source text.
+func patternNames(indices []int, patterns []string) []string {
+	names := make([]string, len(indices))
+	for i, idx := range indices {
+		names[i] = patterns[idx]
+	}
+	return names
+}
+
+func fileContainsPatternMultiline(filePath string, pattern *regexp.Regexp, opts grepOptions) (grepMatch, bool, error) {
+	data, err := readFilePath(filePath)
 	if err != nil {
-		return false, err
+		return grepMatch{}, false, err
+	}
+
+	loc := pattern.FindIndex(data)
+	if loc == nil {
+		return grepMatch{}, false, nil
+	}
+
+	lineNum := bytes.Count(data[:loc[0]], []byte("\n")) + 1
+	lineStart := bytes.LastIndexByte(data[:loc[0]], '\n') + 1
+	lineEnd := loc[1]
+	if idx := bytes.IndexByte(data[lineEnd:], '\n'); idx >= 0 {
+		lineEnd += idx
+	} else {
+		lineEnd = len(data)
+	}
+	lineText := string(data[lineStart:lineEnd])
+
+	allLines := strings.Split(string(data), "\n")
+	matchIdx := lineNum - 1
+	before := contextSlice(allLines, matchIdx-opts.Before, matchIdx)
+	after := contextSlice(allLines, matchIdx+1, matchIdx+1+opts.After)
+
+	return grepMatch{lineNum: lineNum, lineText: lineText, before: before, after: after}, true, nil
+}
+
+func contextSlice(lines []string, start, end int) []string {
+	if start < 0 {
+		start = 0
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+	if start >= end {
+		return nil
+	}
+	return append([]string(nil), lines[start:end]...)
+}
+
+func countPatternInFile(filePath string, pattern *regexp.Regexp, opts grepOptions) (int, error) {
+	if opts.Multiline {
+		data, err := readFilePath(filePath)
+		if err != nil {
+			return 0, err
+		}
+		return len(pattern.FindAllIndex(data, -1)), nil
 	}
-	defer f.Close()
 
-	buf := make([]byte, 8192)
-	n, err := f.Read(buf)
-	if err != nil && err != io.EOF {
-		return false, err
+	file, err := openPath(filePath)
+	if err != nil {
+		return 0, err
 	}
-	buf = buf[:n]
-	for _, b := range buf {
-		if b == 0 {
-			return true, nil
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	count := 0
+	for scanner.Scan() {
+		if pattern.MatchString(scanner.Text()) {
+			count++
+		}
+	}
+	return count, scanner.Err()
+}
+
+// countPatternInFileAC is the fixed_strings counterpart of
+// countPatternInFile.
+func countPatternInFileAC(filePath string, ac *ahoCorasick, opts grepOptions) (int, error) {
+	if opts.Multiline {
+		data, err := readFilePath(filePath)
+		if err != nil {
+			return 0, err
+		}
+		return ac.CountOccurrences(data), nil
+	}
+
+	file, err := openPath(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	count := 0
+	for scanner.Scan() {
+		if ac.MatchAny([]byte(scanner.Text())) {
+			count++
 		}
 	}
-	return false, nil
+	return count, scanner.Err()
 }
 
 func globToRegex(glob string) string {
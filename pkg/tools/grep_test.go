@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBuildGrepFilters(t *testing.T) {
+	filters, err := buildGrepFilters(GrepParams{
+		MaxFileSize: 1024,
+		Exclude:     "*.tmp",
+	})
+	if err != nil {
+		t.Fatalf("buildGrepFilters: %v", err)
+	}
+	if filters.maxFileSize != 1024 {
+		t.Errorf("maxFileSize = %d, want 1024", filters.maxFileSize)
+	}
+	if len(filters.excludes) != 1 || filters.excludes[0] != "*.tmp" {
+		t.Errorf("excludes = %v, want [*.tmp]", filters.excludes)
+	}
+	if !filters.modifiedSince.IsZero() {
+		t.Errorf("modifiedSince = %v, want zero value when unset", filters.modifiedSince)
+	}
+}
+
+func TestBuildGrepFiltersRejectsInvalidModifiedSince(t *testing.T) {
+	if _, err := buildGrepFilters(GrepParams{ModifiedSince: "not-a-time"}); err == nil {
+		t.Errorf("buildGrepFilters: want error for invalid modified_since, got nil")
+	}
+}
+
+// TestRgFileFilterArgsMatchesSelectorSemantics is the ripgrep-vs-regex
+// parity check the max_file_size/exclude/modified_since fix is pinning
+// down: rgFileFilterArgs (ripgrep path) and buildSelector (regex fallback
+// path) must make the same accept/reject decision for the same grepFilters,
+// so switching between the two paths based on whether ripgrep happens to
+// be on PATH doesn't change results.
+func TestRgFileFilterArgsMatchesSelectorSemantics(t *testing.T) {
+	dir := t.TempDir()
+
+	small := filepath.Join(dir, "small.go")
+	if err := os.WriteFile(small, []byte("package x"), 0o644); err != nil {
+		t.Fatalf("write small.go: %v", err)
+	}
+	big := filepath.Join(dir, "big.go")
+	if err := os.WriteFile(big, make([]byte, 2048), 0o644); err != nil {
+		t.Fatalf("write big.go: %v", err)
+	}
+	excluded := filepath.Join(dir, "vendor.go")
+	if err := os.WriteFile(excluded, []byte("package x"), 0o644); err != nil {
+		t.Fatalf("write vendor.go: %v", err)
+	}
+
+	filters := grepFilters{maxFileSize: 1024, excludes: []string{"vendor.go"}}
+	sel := buildSelector(filters)
+
+	smallInfo, _ := os.Stat(small)
+	bigInfo, _ := os.Stat(big)
+	excludedInfo, _ := os.Stat(excluded)
+
+	if !sel(small, smallInfo) {
+		t.Errorf("buildSelector rejected small.go, which is under maxFileSize and not excluded")
+	}
+	if sel(big, bigInfo) {
+		t.Errorf("buildSelector accepted big.go, which exceeds maxFileSize")
+	}
+	if sel(excluded, excludedInfo) {
+		t.Errorf("buildSelector accepted vendor.go, which matches an exclude glob")
+	}
+
+	args := rgFileFilterArgs(filters)
+	if !containsArgPair(args, "--max-filesize", "1024") {
+		t.Errorf("rgFileFilterArgs(%v) missing --max-filesize 1024 matching buildSelector's size cap", args)
+	}
+	if !containsArgPair(args, "--glob", "!vendor.go") {
+		t.Errorf("rgFileFilterArgs(%v) missing --glob !vendor.go matching buildSelector's exclude", args)
+	}
+}
+
+func TestRgFileFilterArgsOmitsUnsetFilters(t *testing.T) {
+	args := rgFileFilterArgs(grepFilters{})
+	if len(args) != 0 {
+		t.Errorf("rgFileFilterArgs(zero value) = %v, want no flags", args)
+	}
+}
+
+func TestBuildSelectorHonorsModifiedSince(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "old.go")
+	if err := os.WriteFile(path, []byte("package x"), 0o644); err != nil {
+		t.Fatalf("write old.go: %v", err)
+	}
+	old := time.Now().Add(-24 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+
+	sel := buildSelector(grepFilters{modifiedSince: time.Now().Add(-time.Hour)})
+	info, _ := os.Stat(path)
+	if sel(path, info) {
+		t.Errorf("buildSelector accepted a file modified before modifiedSince")
+	}
+
+	selPast := buildSelector(grepFilters{modifiedSince: time.Now().Add(-48 * time.Hour)})
+	if !selPast(path, info) {
+		t.Errorf("buildSelector rejected a file modified after modifiedSince")
+	}
+}
+
+func containsArgPair(args []string, flag, value string) bool {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == flag && args[i+1] == value {
+			return true
+		}
+	}
+	return false
+}
@@ -2,9 +2,7 @@ package tools
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
-	"strings"
 )
 
 func absClean(path string) (string, error) {
@@ -22,16 +20,6 @@ func absClean(path string) (string, error) {
 	return abs, nil
 }
 
-func isWithinRoot(rootAbs string, targetAbs string) bool {
-	rootAbs = filepath.Clean(rootAbs)
-	targetAbs = filepath.Clean(targetAbs)
-
-	if rootAbs == targetAbs {
-		return true
-	}
-	if strings.HasPrefix(strings.ToLower(targetAbs), strings.ToLower(rootAbs)+string(os.PathSeparator)) {
-		return true
-	}
-	return false
-}
-
+// isWithinRoot and the path-normalization helpers it builds on
+// (toExtendedPath, longPathName) are platform-specific: see
+// pathguard_unix.go and pathguard_windows.go.
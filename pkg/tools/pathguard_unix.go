@@ -0,0 +1,33 @@
+//go:build !windows
+
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// toExtendedPath is a no-op outside Windows, which has no extended-length
+// path form.
+func toExtendedPath(path string) string {
+	return path
+}
+
+// longPathName is a no-op outside Windows, which has no 8.3 short names.
+func longPathName(path string) (string, error) {
+	return path, nil
+}
+
+func isWithinRoot(rootAbs string, targetAbs string) bool {
+	rootAbs = filepath.Clean(rootAbs)
+	targetAbs = filepath.Clean(targetAbs)
+
+	if rootAbs == targetAbs {
+		return true
+	}
+	if strings.HasPrefix(strings.ToLower(targetAbs), strings.ToLower(rootAbs)+string(os.PathSeparator)) {
+		return true
+	}
+	return false
+}
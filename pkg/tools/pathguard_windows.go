@@ -0,0 +1,122 @@
+//go:build windows
+
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// toExtendedPath converts an absolute, cleaned Windows path to its
+// extended-length form: \\?\C:\... for a local drive, or
+// \\?\UNC\server\share\... for a UNC share. That prefix tells the Win32
+// API to skip MAX_PATH (260 character) validation entirely, which
+// otherwise breaks on repos with deep node_modules trees. Paths already in
+// extended form, and anything that isn't an absolute local or UNC path,
+// are returned unchanged.
+func toExtendedPath(path string) string {
+	if path == "" || strings.HasPrefix(path, `\\?\`) {
+		return path
+	}
+	if strings.HasPrefix(path, `\\`) {
+		return `\\?\UNC\` + strings.TrimPrefix(path, `\\`)
+	}
+	if len(path) >= 2 && path[1] == ':' {
+		return `\\?\` + path
+	}
+	return path
+}
+
+var (
+	modkernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procGetLongPathName = modkernel32.NewProc("GetLongPathNameW")
+)
+
+// longPathName resolves any 8.3 short-name components (e.g. PROGRA~1) in
+// path to their full form via the Win32 GetLongPathNameW API, so two
+// different spellings of the same directory don't fool isWithinRoot's
+// component comparison.
+func longPathName(path string) (string, error) {
+	short, err := syscall.UTF16PtrFromString(toExtendedPath(path))
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]uint16, 4096)
+	for {
+		r1, _, errno := procGetLongPathName.Call(
+			uintptr(unsafe.Pointer(short)),
+			uintptr(unsafe.Pointer(&buf[0])),
+			uintptr(len(buf)),
+		)
+		if r1 == 0 {
+			return "", fmt.Errorf("GetLongPathNameW: %w", errno)
+		}
+		// r1 is the length actually written on success, or the required
+		// buffer length (including the terminator) if buf was too small -
+		// GetLongPathNameW doesn't return 0 in that case, so it has to be
+		// checked explicitly rather than inferred from the return value
+		// alone. Repos with deep node_modules trees routinely exceed the
+		// initial 4096 units once resolved to a long path, so retry with a
+		// bigger buffer instead of slicing past what was written.
+		if need := int(r1); need > len(buf) {
+			buf = make([]uint16, need)
+			continue
+		}
+		return syscall.UTF16ToString(buf[:r1]), nil
+	}
+}
+
+// isWithinRoot reports whether targetAbs is rootAbs itself or lies under
+// it. Both paths are resolved to their long (non-8.3) form first, then
+// compared volume-by-volume and component-by-component rather than as
+// case-folded strings: a plain prefix check treats root "C:\foo" as
+// containing target "C:\foobar", and can wrongly reject a root reached
+// through a different short-name spelling of the same directory.
+func isWithinRoot(rootAbs string, targetAbs string) bool {
+	root := resolveForCompare(rootAbs)
+	target := resolveForCompare(targetAbs)
+
+	rootVol := strings.ToUpper(filepath.VolumeName(root))
+	targetVol := strings.ToUpper(filepath.VolumeName(target))
+	if rootVol != targetVol {
+		return false
+	}
+
+	rootParts := splitComponents(root[len(rootVol):])
+	targetParts := splitComponents(target[len(targetVol):])
+	if len(targetParts) < len(rootParts) {
+		return false
+	}
+	for i, p := range rootParts {
+		if !strings.EqualFold(p, targetParts[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func resolveForCompare(path string) string {
+	path = filepath.Clean(path)
+	if long, err := longPathName(path); err == nil {
+		path = long
+	}
+	// longPathName answers in extended-length form, but a path it can't
+	// resolve (e.g. one that doesn't exist yet) stays in plain form, so
+	// strip the prefix to keep both on the same volume name.
+	if strings.HasPrefix(path, `\\?\UNC\`) {
+		return `\\` + path[len(`\\?\UNC\`):]
+	}
+	return strings.TrimPrefix(path, `\\?\`)
+}
+
+func splitComponents(path string) []string {
+	path = strings.Trim(path, `\`)
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, `\`)
+}
@@ -1,6 +1,8 @@
 package tools
 
 import (
+	"context"
+	"fmt"
 	"path/filepath"
 
 	"github.com/example/demo-tools-bridge/pkg/lsp"
@@ -10,6 +12,7 @@ type Registry struct {
 	RootAbs    string
 	Tools      map[string]BaseTool
 	LSPClients map[string]*lsp.Client
+	Sessions   *lsp.SessionManager
 }
 
 func NewRegistry(root string) (*Registry, error) {
@@ -37,18 +40,47 @@ func NewRegistry(root string) (*Registry, error) {
 
 func (r *Registry) SetLSPClients(clients map[string]*lsp.Client) {
 	r.LSPClients = clients
-	// Attach to tools that can use LSP
+	r.Sessions = lsp.NewSessionManager(clients)
+	// Attach to tools that can use LSP. They all share r.Sessions rather
+	// than the raw map so Acquire/Release reference counts agree no matter
+	// which tool instance opened or closed a given document.
 	if vt, ok := r.Tools[ViewToolName].(*viewTool); ok {
-		vt.lsps = clients
+		vt.sessions = r.Sessions
 	}
 	if wt, ok := r.Tools[WriteToolName].(*writeTool); ok {
-		wt.lsps = clients
+		wt.sessions = r.Sessions
 	}
 	if dt, ok := r.Tools[DiagnosticsToolName].(*diagnosticsTool); ok {
-		dt.lsps = clients
+		dt.sessions = r.Sessions
 	}
 }
 
+// RunStreaming executes name's tool call, preferring its StreamingTool path
+// when it has one (currently only bash) so callers can surface incremental
+// output. Tools without a streaming path run via plain Run, with the single
+// result delivered as the one chunk sent before the channel closes.
+func (r *Registry) RunStreaming(ctx context.Context, name string, call ToolCall) (StreamingToolResponse, error) {
+	tool, ok := r.Tools[name]
+	if !ok {
+		return StreamingToolResponse{}, fmt.Errorf("unknown tool: %s", name)
+	}
+
+	if st, ok := tool.(StreamingTool); ok {
+		return st.RunStreaming(ctx, call)
+	}
+
+	ch := make(chan ToolResponse, 1)
+	go func() {
+		defer close(ch)
+		resp, err := tool.Run(ctx, call)
+		if err != nil {
+			resp = NewTextErrorResponse(err.Error())
+		}
+		ch <- resp
+	}()
+	return StreamingToolResponse{Chunks: ch}, nil
+}
+
 func (r *Registry) List() []ToolInfo {
 	out := make([]ToolInfo, 0, len(r.Tools))
 	for _, t := range r.Tools {
@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 )
 
@@ -15,16 +16,26 @@ type ToolInfo struct {
 type toolResponseType string
 
 const (
-	ToolResponseTypeText toolResponseType = "text"
+	ToolResponseTypeText  toolResponseType = "text"
+	ToolResponseTypeImage toolResponseType = "image"
 )
 
 type ToolResponse struct {
 	Type     toolResponseType `json:"type"`
 	Content  string           `json:"content"`
+	Image    *ImageContent    `json:"image,omitempty"`
 	Metadata string           `json:"metadata,omitempty"`
 	IsError  bool             `json:"is_error"`
 }
 
+// ImageContent is the base64-encoded image payload carried by a
+// ToolResponse whose Type is ToolResponseTypeImage, for downstream clients
+// that accept multimodal input.
+type ImageContent struct {
+	MIMEType string `json:"mime_type"`
+	Data     string `json:"data"`
+}
+
 type TextResponseWithDiagnostics struct {
 	Text               string `json:"text"`
 	FileDiagnostics    string `json:"file_diagnostics,omitempty"`
@@ -46,6 +57,18 @@ func NewTextErrorResponse(content string) ToolResponse {
 	}
 }
 
+// NewImageResponse builds a ToolResponse carrying a base64-encoded image,
+// for tools that can return rendered image content instead of text.
+func NewImageResponse(mimeType string, data []byte) ToolResponse {
+	return ToolResponse{
+		Type: ToolResponseTypeImage,
+		Image: &ImageContent{
+			MIMEType: mimeType,
+			Data:     base64.StdEncoding.EncodeToString(data),
+		},
+	}
+}
+
 func WithResponseMetadata(response ToolResponse, metadata any) ToolResponse {
 	if metadata == nil {
 		return response
@@ -67,3 +90,20 @@ type BaseTool interface {
 	Info() ToolInfo
 	Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 }
+
+// StreamingToolResponse carries a tool call's output as a series of
+// ToolResponse chunks instead of one value returned after the call
+// finishes. Each chunk on Chunks is a snapshot of the output seen so far;
+// the last chunk sent is the final result (with response metadata set, if
+// any) and the channel is closed immediately after it.
+type StreamingToolResponse struct {
+	Chunks <-chan ToolResponse
+}
+
+// StreamingTool is implemented by tools that can report incremental output
+// while they run, rather than only a single ToolResponse once they finish.
+// Registry.RunStreaming uses this to prefer a tool's streaming path when it
+// has one, and falls back to plain Run for tools that don't.
+type StreamingTool interface {
+	RunStreaming(ctx context.Context, call ToolCall) (StreamingToolResponse, error)
+}
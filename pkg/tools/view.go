@@ -2,23 +2,30 @@ package tools
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/example/demo-tools-bridge/pkg/lsp"
-	"github.com/example/demo-tools-bridge/pkg/lsp/protocol"
 )
 
 const (
 	ViewToolName     = "view"
 	MaxReadSize      = 250 * 1024
+	MaxImageSize     = 5 * 1024 * 1024
 	DefaultReadLimit = 2000
 	MaxLineLength    = 2000
+	bytesPerHexRow   = 16
 	viewDescription  = `File viewing tool that reads and displays the contents of files with line numbers, allowing you to examine code, logs, or text data.
 
 WHEN TO USE THIS TOOL:
@@ -30,6 +37,7 @@ HOW TO USE:
 - Provide the path to the file you want to view
 - Optionally specify an offset to start reading from a specific line
 - Optionally specify a limit to control how many lines are read
+- Optionally specify a mode to control how the file is read
 
 FEATURES:
 - Displays file contents with line numbers for easy reference
@@ -37,13 +45,19 @@ FEATURES:
 - Handles large files by limiting the number of lines read
 - Automatically truncates very long lines for better display
 - Suggests similar file names when the requested file isn't found
+- Renders supported image formats (PNG, JPEG, GIF) as image content
+- Falls back to a hex dump for other binary files
+
+HOW MODE WORKS:
+- auto (default): text files are shown as text, images are rendered, other binary files fall back to a hex dump
+- text: always read the file as text, even if it looks binary
+- image: always render the file as an image, falling back to a hex dump for unsupported formats
+- hex: always show a hex dump, using offset/limit as row numbers instead of line numbers
 
 LIMITATIONS:
-- Maximum file size is 250KB
+- Maximum file size is 250KB for text and hex modes, 5MB for images
 - Default reading limit is 2000 lines
 - Lines longer than 2000 characters are truncated
-- Cannot display binary files or images
-- Images can be identified but not displayed
 
 TIPS:
 - Use with Glob tool to first find files you want to view
@@ -55,6 +69,7 @@ type ViewParams struct {
 	FilePath string `json:"file_path"`
 	Offset   int    `json:"offset"`
 	Limit    int    `json:"limit"`
+	Mode     string `json:"mode"`
 }
 
 type ViewResponseMetadata struct {
@@ -62,13 +77,28 @@ type ViewResponseMetadata struct {
 	Content  string `json:"content"`
 }
 
+type ImageResponseMetadata struct {
+	FilePath string `json:"file_path"`
+	MIMEType string `json:"mime_type"`
+	Bytes    int    `json:"bytes"`
+	Width    int    `json:"width,omitempty"`
+	Height   int    `json:"height,omitempty"`
+}
+
+type HexResponseMetadata struct {
+	FilePath string `json:"file_path"`
+	Offset   int64  `json:"offset"`
+	Length   int64  `json:"length"`
+	Total    int64  `json:"total"`
+}
+
 type viewTool struct {
-	root string
-	lsps map[string]*lsp.Client
+	root     string
+	sessions *lsp.SessionManager
 }
 
 func NewViewTool(root string) BaseTool {
-	return &viewTool{root: root, lsps: map[string]*lsp.Client{}}
+	return &viewTool{root: root, sessions: lsp.NewSessionManager(map[string]*lsp.Client{})}
 }
 
 func (v *viewTool) Info() ToolInfo {
@@ -82,11 +112,15 @@ func (v *viewTool) Info() ToolInfo {
 			},
 			"offset": map[string]any{
 				"type":        "integer",
-				"description": "The line number to start reading from (0-based)",
+				"description": "The line number to start reading from (0-based), or the row number in hex mode",
 			},
 			"limit": map[string]any{
 				"type":        "integer",
-				"description": "The number of lines to read (defaults to 2000)",
+				"description": "The number of lines to read (defaults to 2000), or the number of rows in hex mode",
+			},
+			"mode": map[string]any{
+				"type":        "string",
+				"description": "How to read the file: auto (default), text, image, or hex",
 			},
 		},
 		Required: []string{"file_path"},
@@ -101,6 +135,11 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	if params.FilePath == "" {
 		return NewTextErrorResponse("file_path is required"), nil
 	}
+	switch params.Mode {
+	case "", "auto", "text", "image", "hex":
+	default:
+		return NewTextErrorResponse(fmt.Sprintf("invalid mode: %s (expected auto, text, image, or hex)", params.Mode)), nil
+	}
 
 	fileAbs, err := absClean(params.FilePath)
 	if err != nil {
@@ -110,13 +149,13 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		return NewTextErrorResponse("path is outside workspace root"), nil
 	}
 
-	fileInfo, err := os.Stat(fileAbs)
+	fileInfo, err := statPath(fileAbs)
 	if err != nil {
 		if os.IsNotExist(err) {
 			dir := filepath.Dir(fileAbs)
 			base := filepath.Base(fileAbs)
 
-			dirEntries, dirErr := os.ReadDir(dir)
+			dirEntries, dirErr := readDirPath(dir)
 			if dirErr == nil {
 				var suggestions []string
 				baseLower := strings.ToLower(base)
@@ -142,25 +181,48 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	if fileInfo.IsDir() {
 		return NewTextErrorResponse(fmt.Sprintf("Path is a directory, not a file: %s", fileAbs)), nil
 	}
-	if fileInfo.Size() > MaxReadSize {
-		return NewTextErrorResponse(fmt.Sprintf("File is too large (%d bytes). Maximum size is %d bytes", fileInfo.Size(), MaxReadSize)), nil
+
+	mode := params.Mode
+	if mode == "" || mode == "auto" {
+		if isImage, _ := isImageFile(fileAbs); isImage {
+			mode = "image"
+		} else if looksBinaryContentType(fileAbs) {
+			mode = "hex"
+		} else {
+			mode = "text"
+		}
 	}
-	if isImage, imageType := isImageFile(fileAbs); isImage {
-		return NewTextErrorResponse(fmt.Sprintf("This is an image file of type: %s", imageType)), nil
+
+	switch mode {
+	case "image":
+		if fileInfo.Size() > MaxImageSize {
+			return NewTextErrorResponse(fmt.Sprintf("Image is too large (%d bytes). Maximum size is %d bytes", fileInfo.Size(), MaxImageSize)), nil
+		}
+		return v.viewImage(fileAbs, fileInfo, params.Offset, params.Limit)
+	case "hex":
+		return v.viewHex(fileAbs, fileInfo, params.Offset, params.Limit)
+	default:
+		if fileInfo.Size() > MaxReadSize {
+			return NewTextErrorResponse(fmt.Sprintf("File is too large (%d bytes). Maximum size is %d bytes", fileInfo.Size(), MaxReadSize)), nil
+		}
+		return v.viewText(ctx, fileAbs, params.Offset, params.Limit)
 	}
-	if params.Limit <= 0 {
-		params.Limit = DefaultReadLimit
+}
+
+func (v *viewTool) viewText(ctx context.Context, fileAbs string, offset, limit int) (ToolResponse, error) {
+	if limit <= 0 {
+		limit = DefaultReadLimit
 	}
 
-	content, lineCount, err := readTextFile(fileAbs, params.Offset, params.Limit)
+	content, lineCount, err := readTextFile(fileAbs, offset, limit)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("error reading file: %w", err)
 	}
 
 	out := "<file>\n"
-	out += addLineNumbers(content, params.Offset+1)
-	if lineCount > params.Offset+len(strings.Split(content, "\n")) {
-		out += fmt.Sprintf("\n\n(File has more lines. Use 'offset' parameter to read beyond line %d)", params.Offset+len(strings.Split(content, "\n")))
+	out += addLineNumbers(content, offset+1)
+	if lineCount > offset+len(strings.Split(content, "\n")) {
+		out += fmt.Sprintf("\n\n(File has more lines. Use 'offset' parameter to read beyond line %d)", offset+len(strings.Split(content, "\n")))
 	}
 	out += "\n</file>\n"
 
@@ -175,6 +237,86 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	), nil
 }
 
+func (v *viewTool) viewImage(fileAbs string, fileInfo os.FileInfo, offset, limit int) (ToolResponse, error) {
+	data, err := readFilePath(fileAbs)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error reading file: %w", err)
+	}
+
+	mimeType := imageMIMEType(fileAbs, data)
+	if mimeType == "" {
+		// Extension said image (e.g. BMP, SVG, WebP), but it's not a format
+		// the image package can decode. Render a hex dump instead of
+		// refusing outright, the same as any other binary file.
+		return v.viewHex(fileAbs, fileInfo, offset, limit)
+	}
+
+	var width, height int
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		width, height = cfg.Width, cfg.Height
+	}
+
+	return WithResponseMetadata(
+		NewImageResponse(mimeType, data),
+		ImageResponseMetadata{
+			FilePath: fileAbs,
+			MIMEType: mimeType,
+			Bytes:    len(data),
+			Width:    width,
+			Height:   height,
+		},
+	), nil
+}
+
+func (v *viewTool) viewHex(fileAbs string, fileInfo os.FileInfo, offset, limit int) (ToolResponse, error) {
+	if fileInfo.Size() > MaxReadSize {
+		return NewTextErrorResponse(fmt.Sprintf("File is too large (%d bytes). Maximum size is %d bytes", fileInfo.Size(), MaxReadSize)), nil
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = DefaultReadLimit
+	}
+
+	start := int64(offset) * bytesPerHexRow
+	length := int64(limit) * bytesPerHexRow
+	if start > fileInfo.Size() {
+		start = fileInfo.Size()
+	}
+	if start+length > fileInfo.Size() {
+		length = fileInfo.Size() - start
+	}
+
+	file, err := openPath(fileAbs)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error opening file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		return ToolResponse{}, fmt.Errorf("error seeking file: %w", err)
+	}
+	buf := make([]byte, length)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return ToolResponse{}, fmt.Errorf("error reading file: %w", err)
+	}
+	buf = buf[:n]
+
+	out := "<file>\n" + hexDump(buf, start) + "\n</file>\n"
+
+	return WithResponseMetadata(
+		NewTextResponse(out),
+		HexResponseMetadata{
+			FilePath: fileAbs,
+			Offset:   start,
+			Length:   int64(n),
+			Total:    fileInfo.Size(),
+		},
+	), nil
+}
+
 func addLineNumbers(content string, startLine int) string {
 	if content == "" {
 		return ""
@@ -195,8 +337,43 @@ func addLineNumbers(content string, startLine int) string {
 	return strings.Join(result, "\n")
 }
 
+// hexDump renders data as rows of bytesPerHexRow bytes, each shown as an
+// offset, hex bytes, and an ASCII gutter, matching the classic `hexdump -C`
+// layout so the output is familiar to read.
+func hexDump(data []byte, baseOffset int64) string {
+	var b strings.Builder
+	for i := 0; i < len(data); i += bytesPerHexRow {
+		end := i + bytesPerHexRow
+		if end > len(data) {
+			end = len(data)
+		}
+		row := data[i:end]
+		fmt.Fprintf(&b, "%08x  ", baseOffset+int64(i))
+		for j := 0; j < bytesPerHexRow; j++ {
+			if j < len(row) {
+				fmt.Fprintf(&b, "%02x ", row[j])
+			} else {
+				b.WriteString("   ")
+			}
+			if j == bytesPerHexRow/2-1 {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(" |")
+		for _, c := range row {
+			if c >= 0x20 && c < 0x7f {
+				b.WriteByte(c)
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		b.WriteString("|\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
 func readTextFile(filePath string, offset, limit int) (string, int, error) {
-	file, err := os.Open(filePath)
+	file, err := openPath(filePath)
 	if err != nil {
 		return "", 0, err
 	}
@@ -265,39 +442,65 @@ func isImageFile(filePath string) (bool, string) {
 	}
 }
 
+// imageMIMEType returns the MIME type to use for data if it's a format the
+// image package (and so viewImage) can decode, or "" otherwise. It sniffs
+// the content rather than trusting the file extension, since a renamed or
+// mislabeled file should still render if its bytes decode.
+func imageMIMEType(filePath string, data []byte) string {
+	switch http.DetectContentType(data) {
+	case "image/png":
+		return "image/png"
+	case "image/jpeg":
+		return "image/jpeg"
+	case "image/gif":
+		return "image/gif"
+	default:
+		return ""
+	}
+}
+
+// looksBinaryContentType reports whether the file at filePath sniffs as
+// binary, mirroring pkg/selector's detectContentType/looksBinary approach
+// of inspecting the leading bytes rather than trusting the extension.
+func looksBinaryContentType(filePath string) bool {
+	file, err := openPath(filePath)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	buf := make([]byte, 512)
+	n, err := file.Read(buf)
+	if err != nil && err != io.EOF {
+		return false
+	}
+	buf = buf[:n]
+
+	contentType := http.DetectContentType(buf)
+	if strings.HasPrefix(contentType, "text/") || contentType == "application/json" || contentType == "application/xml" {
+		return false
+	}
+	return bytes.ContainsRune(buf, 0)
+}
+
 func (v *viewTool) collectDiagnostics(ctx context.Context, filePath string) string {
-	if len(v.lsps) == 0 {
+	clients := v.sessions.Clients()
+	if len(clients) == 0 {
 		return ""
 	}
 	var lines []string
-	for name, client := range v.lsps {
-		_ = client.OpenFile(ctx, filePath)
+	for name, client := range clients {
+		sess, err := client.Acquire(ctx, filePath)
+		if err != nil {
+			continue
+		}
 		ds, err := client.GetDiagnosticsForFile(ctx, filePath)
+		sess.Release()
 		if err != nil {
 			continue
 		}
 		for _, d := range ds {
-			severity := "Info"
-			switch d.Severity {
-			case protocol.SeverityError:
-				severity = "Error"
-			case protocol.SeverityWarning:
-				severity = "Warn"
-			case protocol.SeverityHint:
-				severity = "Hint"
-			case protocol.SeverityInformation:
-				severity = "Info"
-			}
-			source := d.Source
-			if source == "" {
-				source = name
-			}
-			loc := fmt.Sprintf("%s:%d:%d", filePath, d.Range.Start.Line+1, d.Range.Start.Character+1)
-			code := ""
-			if d.Code != nil {
-				code = fmt.Sprintf("[%v]", d.Code)
-			}
-			lines = append(lines, fmt.Sprintf("%s: %s [%s]%s %s", severity, loc, source, code, d.Message))
+			lines = append(lines, formatDiagnostic(filePath, d, name))
 		}
 	}
 	return strings.Join(lines, "\n")
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/example/demo-tools-bridge/pkg/ignore"
 	"github.com/example/demo-tools-bridge/pkg/lsp"
 )
 
@@ -15,6 +16,7 @@ const WriteToolName = "write"
 type WriteParams struct {
 	FilePath string `json:"file_path"`
 	Content  string `json:"content"`
+	Force    bool   `json:"force"`
 }
 
 type WriteResponseMetadata struct {
@@ -23,12 +25,16 @@ type WriteResponseMetadata struct {
 }
 
 type writeTool struct {
-	root string
-	lsps map[string]*lsp.Client
+	root     string
+	sessions *lsp.SessionManager
+	// rejectIgnored, when true, refuses writes to paths matched by the
+	// workspace's .gitignore/.ignore/.lspignore rules unless the caller
+	// sets Force.
+	rejectIgnored bool
 }
 
 func NewWriteTool(root string) BaseTool {
-	return &writeTool{root: root, lsps: map[string]*lsp.Client{}}
+	return &writeTool{root: root, sessions: lsp.NewSessionManager(map[string]*lsp.Client{}), rejectIgnored: true}
 }
 
 func (w *writeTool) Info() ToolInfo {
@@ -44,6 +50,10 @@ func (w *writeTool) Info() ToolInfo {
 				"type":        "string",
 				"description": "The content to write to the file",
 			},
+			"force": map[string]any{
+				"type":        "boolean",
+				"description": "If true, write even if the path matches the workspace's ignore rules",
+			},
 		},
 		Required: []string{"file_path", "content"},
 	}
@@ -69,8 +79,20 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	if w.root != "" && !isWithinRoot(w.root, absPath) {
 		return NewTextErrorResponse("path is outside workspace root"), nil
 	}
+	if w.rejectIgnored && !params.Force && w.root != "" {
+		ig, err := ignore.ForDir(w.root)
+		if err != nil {
+			return ToolResponse{}, fmt.Errorf("error loading ignore rules: %w", err)
+		}
+		if ignored, fromPattern := ig.Match(absPath); ignored {
+			return NewTextErrorResponse(fmt.Sprintf("path is ignored by %s: %s (set force=true to write anyway)", fromPattern, absPath)), nil
+		}
+		if ignored, fromPattern := dirChainIgnored(ig, absPath); ignored {
+			return NewTextErrorResponse(fmt.Sprintf("path is ignored by %s: %s (set force=true to write anyway)", fromPattern, absPath)), nil
+		}
+	}
 
-	info, err := os.Stat(absPath)
+	info, err := statPath(absPath)
 	if err == nil && info.IsDir() {
 		return NewTextErrorResponse(fmt.Sprintf("path is a directory, not a file: %s", absPath)), nil
 	} else if err != nil && !os.IsNotExist(err) {
@@ -78,21 +100,21 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	}
 
 	dir := filepath.Dir(absPath)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
+	if err := mkdirAllPath(dir, 0o755); err != nil {
 		return ToolResponse{}, fmt.Errorf("error creating directory: %w", err)
 	}
 
-	if err := os.WriteFile(absPath, []byte(params.Content), 0o644); err != nil {
+	if err := writeFilePath(absPath, []byte(params.Content), 0o644); err != nil {
 		return ToolResponse{}, fmt.Errorf("error writing file: %w", err)
 	}
 
-	for _, client := range w.lsps {
-		if client.IsFileOpen(absPath) {
-			_ = client.NotifyChange(ctx, absPath)
-		} else {
-			_ = client.OpenFile(ctx, absPath)
-			_ = client.NotifyChange(ctx, absPath)
+	for _, client := range w.sessions.Clients() {
+		sess, err := client.Acquire(ctx, absPath)
+		if err != nil {
+			continue
 		}
+		_ = sess.Sync(ctx, []byte(params.Content))
+		sess.Release()
 	}
 
 	result := fmt.Sprintf("File successfully written: %s", absPath)
@@ -104,3 +126,33 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		},
 	), nil
 }
+
+// dirChainIgnored reports whether any directory between absPath's parent
+// and ig's root is matched by an ignore pattern. Match alone only
+// evaluates the exact path it's given, so a dirOnly pattern like "build/"
+// never matches a leaf file path directly; callers have to apply Match to
+// every directory on the way down to get the "everything beneath is
+// ignored" behavior, the same way grep's filepath.Walk + SkipDir does.
+func dirChainIgnored(ig *ignore.Ignore, absPath string) (bool, string) {
+	root := filepath.Clean(ig.Root())
+
+	var dirs []string
+	for dir := filepath.Dir(absPath); ; {
+		dirs = append(dirs, dir)
+		if dir == root {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if ignored, fromPattern := ig.Match(dirs[i]); ignored {
+			return true, fromPattern
+		}
+	}
+	return false, ""
+}